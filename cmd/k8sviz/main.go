@@ -4,107 +4,652 @@
 package main
 
 import (
-	"flag"
+	"context"
 	"fmt"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/mkimuram/k8sviz/pkg/graph"
 	"github.com/mkimuram/k8sviz/pkg/resources"
+	"github.com/mkimuram/k8sviz/pkg/server"
+	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
 const (
-	defaultNamespace   = "namespace"
+	defaultNamespace   = ""
 	defaultOutFile     = "k8sviz.out"
 	defaultOutType     = "dot"
-	descNamespaceOpt   = "namespace to visualize"
-	descOutFileOpt     = "output filename"
-	descOutTypeOpt     = "type of output"
-	descShortOptSuffix = " (shorthand)"
+	defaultRankDir     = "TD"
+	defaultLayout      = "dot"
+	descNamespaceOpt   = "namespace to visualize (comma separated for multiple namespaces, the one of the current context if empty)"
+	descOutFileOpt     = "output filename (- for standard output, comma separated for multiple types)"
+	descOutTypeOpt     = "type of output (dot, mermaid, json or a format supported by dot command, comma separated for multiple files)"
+	descRankDirOpt     = "direction of the layout (TB, BT, LR or RL)"
+	descManifestOpt    = "manifest file or directory to visualize instead of the cluster"
+	descNoPodColorOpt  = "disable coloring pods by their phase"
+	descNoTypeColorOpt = "disable the boxes of resources colored by their types"
+	descHideSucceedOpt = "hide pods in Succeeded phase, like the ones of completed jobs"
+	descHideFailedOpt  = "hide pods in Failed phase"
+	descReplicasOpt    = "show ready/desired replicas of workloads"
+	descSelectorOpt    = "label selector to filter resources, like app=frontend"
+	descNameRegexOpt   = "regular expression to filter resources by their names, like ^api-"
+	descViewOpt        = "show only the resource types for the view (storage, networking, workloads or rbac)"
+	descExcludeOpt     = "comma separated resource types not to show, like svc,ing"
+	descIncludeOpt     = "comma separated resource types to show only, like deploy,svc (can't be used with --exclude or --view)"
+	descDiffOpt        = "manifest file or directory of the old resources to show what are added (green) or removed (red)"
+	descSinceOpt       = "show only resources created within the duration or after the time, like 1h or 2021-06-01T00:00:00Z"
+	descEndpointsOpt   = "connect services and pods based on endpoints instead of selectors"
+	descEpReadinessOpt = "draw edges from services to pods that aren't ready with dashed red line (needs --endpoints)"
+	descURLTemplateOpt = "URL template of nodes to make them clickable in svg, like https://console/ns/{namespace}/{kind}/{name}"
+	descNetpolPeersOpt = "show pods selected by ingress and egress rules of networkpolicies"
+	descLayoutOpt      = "graphviz layout engine to plot (dot, neato, fdp, sfdp, circo, twopi, patchwork or osage)"
+	descDirOpt         = "directory that has icons directory to use instead of the embedded icons"
+	descIconOpt        = "comma separated icon files to override, like pod=/path/to/pod.png,svc=/path/to/svc.png"
+	descGroupOpt       = "annotation key to group resources by its value, like app.kubernetes.io/part-of"
+	descFontNameOpt    = "font of the labels, like Helvetica (the default of graphviz if empty)"
+	descFontSizeOpt    = "font size of the labels in points, like 16 (the default of graphviz if 0)"
+	descNodeMarginOpt  = "margin around the labels of nodes in inches, like 0.2 (the default of graphviz if 0)"
+	descTransparentOpt = "make the background transparent to embed the graph in colored backgrounds"
+	descGraphAttrOpt   = "comma separated graphviz attributes of the graph to override, like bgcolor=transparent,dpi=150"
+	descLegendOpt      = "add the legend of edges and icons to the graph (not for mermaid and json)"
+	descIconSuffixOpt  = "suffix of icon files in the icons directory, like -128.svg (needs --dir for other than -128.png)"
+	descPdbBudgetOpt   = "show minAvailable or maxUnavailable of poddisruptionbudgets"
+	descSvcTypeOpt     = "show types of services with external IPs or node ports"
+	descScheduleOpt    = "show schedules of cronjobs, with descriptions of common ones like \"every 5 minutes\""
+	descImagesOpt      = "show images of containers in pods"
+	descCtrPortsOpt    = "show ports of containers in pods"
+	descAgeOpt         = "show the time since the creation of resources"
+	descSvcPortsOpt    = "show ports of services on the edges to pods"
+	descTooltipsOpt    = "show labels of resources as tooltips in svg"
+	descTooltipAnnoOpt = "add annotations of resources to tooltips"
+	descAllOwnersOpt   = "show owners other than controllers with dotted edges"
+	descCollapseOpt    = "show pods owned by the same controller as a single node"
+	descCollapseRsOpt  = "hide replicasets of deployments, and connect deployments to pods directly"
+	descTemplatesOpt   = "show pod templates of workloads without pods, like deployments scaled to zero, as dashed pods"
+	descNoClusterOpt   = "omit the box of namespaces"
+	descClusterLblOpt  = "text shown for namespaces instead of their names, like \"Production - {namespace}\""
+	descValidateOpt    = "fail if resources refer to the ones that aren't found, like a missing pvc"
+	descRanksOpt       = "semicolon separated ranks of comma separated resource types from the top, like \"ing;svc;deploy,sts\""
+	descDryRunOpt      = "generate the graph without output, and fail if there are warnings"
+	descSplinesOpt     = "how edges are drawn (none, line, false, polyline, curved, ortho, spline or true)"
+	descOverlapOpt     = "how overlapping nodes are removed by layout engines other than dot, like false or scale"
+	descTitleOpt       = "title shown at the top, where {namespaces}, {context} and {time} are replaced, like \"{context}: {namespaces} at {time}\""
+	descFocusOpt       = "resource to show only with the resources around it, like deploy/my-deployment"
+	descFocusDepthOpt  = "number of edges to follow from the resource to focus on"
+	descMaxNodesOpt    = "fail if the graph has more nodes than this, to avoid plotting too large graph (no limit if 0)"
+	descContinueOpt    = "continue plotting the other files on failures to plot multiple files"
+	descMkdirOpt       = "create the directories of output files if they don't exist"
+	descExtVolumesOpt  = "show hostPath and CSI volumes of pods"
+	descExtHostsOpt    = "show hosts outside the cluster that ExternalName services point to"
+	descServeOpt       = "address to serve GET /render?namespace=X&format=svg instead of writing files, like :8080"
+	descRetriesOpt     = "number of retries to get resources on transient errors, like timeouts"
+	descRetryIntvlOpt  = "time to wait before the first retry to get resources, doubled for each retry"
+	descProgressOpt    = "show progress of getting resources and plotting to stderr"
+	descOpenShiftOpt   = "show deploymentconfigs and routes of OpenShift"
+	descSnapshotsOpt   = "show volumesnapshots and volumesnapshotcontents of CSI, skipped if their CRDs aren't installed"
+	descNodesOpt       = "show nodes that pods run on, which needs the permission to list nodes"
+	descWatchOpt       = "watch resources and render the outputs again on changes until interrupted"
+	descTimeoutOpt     = "time limit to plot with the layout engine, like 30s (no limit if 0)"
 )
 
 var (
-	clientset *kubernetes.Clientset
-	dir       string
+	clientset  *kubernetes.Clientset
+	namespaces []string
+	outTypes   []string
+	outFiles   []string
 	// Flags
 	namespace string
 	outFile   string
 	outType   string
+	manifest  string
+	diff      string
+	selector  string
+	nameRegex string
+	since     string
+	sinceTime time.Time
+	exclude   string
+	include   string
+	dir       string
+	icon      string
+	graphAttr string
+	ranks     string
+	validate  bool
+	dryRun    bool
+	serve     string
+	progress  bool
+	openShift bool
+	snapshots bool
+	nodes     bool
+	watch     bool
+	graphOpts graph.Options
+	fetchOpts = resources.FetchOptions{ContinueOnError: true}
 )
 
 func init() {
-	var (
-		err        error
-		kubeconfig string
-	)
-	if home := os.Getenv("HOME"); home != "" {
-		flag.StringVar(&kubeconfig, "kubeconfig", filepath.Join(home, ".kube", "config"), "absolute path to the kubeconfig file")
-	} else {
-		flag.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
-	}
-	flag.StringVar(&namespace, "namespace", defaultNamespace, descNamespaceOpt)
-	flag.StringVar(&namespace, "n", defaultNamespace, descNamespaceOpt+descShortOptSuffix)
-	flag.StringVar(&outFile, "outfile", defaultOutFile, descOutFileOpt)
-	flag.StringVar(&outFile, "o", defaultOutFile, descOutFileOpt+descShortOptSuffix)
-	flag.StringVar(&outType, "type", defaultOutType, descOutTypeOpt)
-	flag.StringVar(&outType, "t", defaultOutType, descOutTypeOpt+descShortOptSuffix)
-	flag.Parse()
-
-	// use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to build config from %q: %v\n", kubeconfig, err)
+	var err error
+	flags := pflag.NewFlagSet(os.Args[0], pflag.ContinueOnError)
+	// kubectl global flags, like --kubeconfig, --context, --cluster, --user
+	// and --as, for k8sviz to work as a kubectl plugin. Namespaces are got
+	// with its own flag instead, as multiple namespaces can be specified.
+	configFlags := genericclioptions.NewConfigFlags(true)
+	configFlags.Namespace = nil
+	configFlags.AddFlags(flags)
+	flags.StringVarP(&namespace, "namespace", "n", defaultNamespace, descNamespaceOpt)
+	flags.StringVarP(&outFile, "outfile", "o", defaultOutFile, descOutFileOpt)
+	flags.StringVarP(&outType, "type", "t", defaultOutType, descOutTypeOpt)
+	flags.StringVar(&graphOpts.RankDir, "rankdir", defaultRankDir, descRankDirOpt)
+	flags.StringVarP(&manifest, "filename", "f", "", descManifestOpt)
+	flags.StringVarP(&selector, "selector", "l", "", descSelectorOpt)
+	flags.StringVar(&nameRegex, "name-regex", "", descNameRegexOpt)
+	flags.StringVar(&since, "since", "", descSinceOpt)
+	flags.StringVar(&diff, "diff", "", descDiffOpt)
+	flags.BoolVar(&graphOpts.DisablePodPhaseColor, "no-pod-color", false, descNoPodColorOpt)
+	flags.BoolVar(&graphOpts.DisableTypeColor, "no-type-color", false, descNoTypeColorOpt)
+	flags.BoolVar(&graphOpts.HideSucceededPods, "hide-succeeded", false, descHideSucceedOpt)
+	flags.BoolVar(&graphOpts.HideFailedPods, "hide-failed", false, descHideFailedOpt)
+	flags.BoolVar(&graphOpts.ShowReplicas, "replicas", false, descReplicasOpt)
+	flags.BoolVar(&graphOpts.ShowNonControllerOwners, "all-owners", false, descAllOwnersOpt)
+	flags.BoolVar(&graphOpts.ShowDisruptionBudget, "pdb-budget", false, descPdbBudgetOpt)
+	flags.BoolVar(&graphOpts.ShowAge, "age", false, descAgeOpt)
+	flags.BoolVar(&graphOpts.ShowImages, "images", false, descImagesOpt)
+	flags.BoolVar(&graphOpts.ShowContainerPorts, "container-ports", false, descCtrPortsOpt)
+	flags.BoolVar(&graphOpts.ShowSchedule, "schedule", false, descScheduleOpt)
+	flags.BoolVar(&graphOpts.CollapsePods, "collapse-pods", false, descCollapseOpt)
+	flags.BoolVar(&graphOpts.CollapseReplicaSets, "collapse-rs", false, descCollapseRsOpt)
+	flags.BoolVar(&graphOpts.ShowPodTemplates, "pod-templates", false, descTemplatesOpt)
+	flags.BoolVar(&graphOpts.OmitCluster, "no-cluster", false, descNoClusterOpt)
+	flags.StringVar(&graphOpts.ClusterLabel, "cluster-label", "", descClusterLblOpt)
+	flags.StringVar(&graphOpts.Focus, "focus", "", descFocusOpt)
+	flags.IntVar(&graphOpts.FocusDepth, "focus-depth", 1, descFocusDepthOpt)
+	flags.BoolVar(&graphOpts.ShowExternalVolumes, "external-volumes", false, descExtVolumesOpt)
+	flags.BoolVar(&graphOpts.ShowExternalHosts, "external-hosts", false, descExtHostsOpt)
+	flags.StringVar(&exclude, "exclude", "", descExcludeOpt)
+	flags.StringVar(&include, "include", "", descIncludeOpt)
+	flags.StringVar(&graphOpts.View, "view", "", descViewOpt)
+	flags.BoolVar(&graphOpts.UseEndpoints, "endpoints", false, descEndpointsOpt)
+	flags.BoolVar(&graphOpts.ShowEndpointReadiness, "endpoint-readiness", false, descEpReadinessOpt)
+	flags.BoolVar(&graphOpts.ShowServiceType, "svc-type", false, descSvcTypeOpt)
+	flags.BoolVar(&graphOpts.ShowServicePorts, "svc-ports", false, descSvcPortsOpt)
+	flags.BoolVar(&graphOpts.ShowTooltips, "tooltips", false, descTooltipsOpt)
+	flags.BoolVar(&graphOpts.ShowTooltipAnnotations, "tooltip-annotations", false, descTooltipAnnoOpt)
+	flags.StringVar(&graphOpts.URLTemplate, "url-template", "", descURLTemplateOpt)
+	flags.BoolVar(&graphOpts.ShowNetworkPolicyPeers, "netpol-peers", false, descNetpolPeersOpt)
+	flags.StringVar(&ranks, "ranks", "", descRanksOpt)
+	flags.StringVar(&graphOpts.Layout, "layout", defaultLayout, descLayoutOpt)
+	flags.BoolVar(&validate, "validate", false, descValidateOpt)
+	flags.BoolVar(&dryRun, "dry-run", false, descDryRunOpt)
+	flags.StringVar(&serve, "serve", "", descServeOpt)
+	flags.IntVar(&fetchOpts.Retries, "retries", 0, descRetriesOpt)
+	flags.DurationVar(&fetchOpts.RetryInterval, "retry-interval", time.Second, descRetryIntvlOpt)
+	flags.StringVar(&graphOpts.Splines, "splines", "", descSplinesOpt)
+	flags.StringVar(&graphOpts.Overlap, "overlap", "", descOverlapOpt)
+	flags.IntVar(&graphOpts.MaxNodes, "max-nodes", 0, descMaxNodesOpt)
+	flags.BoolVar(&graphOpts.ContinuePlotOnError, "continue-on-error", false, descContinueOpt)
+	flags.BoolVar(&graphOpts.CreateOutputDir, "mkdir", false, descMkdirOpt)
+	flags.DurationVar(&graphOpts.PlotTimeout, "timeout", 0, descTimeoutOpt)
+	flags.BoolVar(&progress, "progress", false, descProgressOpt)
+	flags.BoolVar(&openShift, "openshift", false, descOpenShiftOpt)
+	flags.BoolVar(&snapshots, "volume-snapshots", false, descSnapshotsOpt)
+	flags.BoolVar(&nodes, "nodes", false, descNodesOpt)
+	flags.BoolVar(&watch, "watch", false, descWatchOpt)
+	flags.StringVarP(&dir, "dir", "d", "", descDirOpt)
+	flags.StringVar(&icon, "icon", "", descIconOpt)
+	flags.StringVar(&graphOpts.Title, "title", "", descTitleOpt)
+	flags.StringVar(&graphOpts.GroupAnnotation, "group-annotation", "", descGroupOpt)
+	flags.StringVar(&graphOpts.FontName, "font", "", descFontNameOpt)
+	flags.Float64Var(&graphOpts.FontSize, "font-size", 0, descFontSizeOpt)
+	flags.Float64Var(&graphOpts.NodeMargin, "node-margin", 0, descNodeMarginOpt)
+	flags.BoolVar(&graphOpts.TransparentBackground, "transparent", false, descTransparentOpt)
+	flags.StringVar(&graphAttr, "graph-attr", "", descGraphAttrOpt)
+	flags.BoolVar(&graphOpts.ShowLegend, "legend", false, descLegendOpt)
+	flags.StringVar(&graphOpts.IconSuffix, "icon-suffix", "", descIconSuffixOpt)
+	if err := flags.Parse(compatArgs(flags, os.Args[1:])); err != nil {
+		if err == pflag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Failed to parse flags: %v\nUsage of %s:\n", err, os.Args[0])
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	if progress {
+		fetchOpts.Progress = os.Stderr
+		graphOpts.Progress = os.Stderr
+	}
+	if nodes {
+		fetchOpts.Nodes = true
+		graphOpts.ShowNodes = true
+	}
+	if watch && (manifest != "" || diff != "" || serve != "" || dryRun || validate || openShift || snapshots) {
+		fmt.Fprintf(os.Stderr, "Failed to watch: --watch can't be used with --filename, --diff, --serve, --dry-run, --validate, --openshift or --volume-snapshots\n")
 		os.Exit(1)
 	}
 
-	// create the clientset
-	clientset, err = kubernetes.NewForConfig(config)
+	// use the namespace of the context, if not specified
+	// The kubeconfig is loaded by resources package in the same way as configFlags.
+	if namespace == "" {
+		namespace, err = resources.ContextNamespace(*configFlags.KubeConfig, *configFlags.Context)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get namespace: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	namespaces = uniqueList(splitList(namespace))
+	outTypes, outFiles = splitList(outType), splitList(outFile)
+	if len(outTypes) != len(outFiles) {
+		fmt.Fprintf(os.Stderr, "Failed to parse outputs: %d types for %d files\n", len(outTypes), len(outFiles))
+		os.Exit(1)
+	}
+	graphOpts.ExcludeTypes = splitList(exclude)
+	graphOpts.IncludeTypes = splitList(include)
+	graphOpts.Ranks = splitRanks(ranks)
+	graphOpts.Icons, err = splitMap(icon)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse icons %q: %v\n", icon, err)
+		os.Exit(1)
+	}
+	graphOpts.GraphAttrs, err = splitMap(graphAttr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse graph attributes %q: %v\n", graphAttr, err)
+		os.Exit(1)
+	}
+	sinceTime, err = parseSince(since, time.Now())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create client from %q: %v\n", kubeconfig, err)
+		fmt.Fprintf(os.Stderr, "Failed to parse since %q: %v\n", since, err)
 		os.Exit(1)
 	}
 
-	// test connectivity for k8s cluster and the namespace
-	_, err = clientset.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	// resources are read from manifests in main, instead of the k8s cluster
+	if manifest != "" {
+		// the manifests are shown as the context in the title
+		graphOpts.Context = manifest
+		if serve != "" {
+			fmt.Fprintf(os.Stderr, "Failed to serve %q: manifests can't be served\n", serve)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// use the context in kubeconfig, or in-cluster config if no kubeconfig is found
+	graphOpts.Context = *configFlags.Context
+	if graphOpts.Context == "" {
+		graphOpts.Context, err = resources.CurrentContext(*configFlags.KubeConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get context: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	config, err := configFlags.ToRESTConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get namespace %q: %v\n", namespace, err)
+		fmt.Fprintf(os.Stderr, "Failed to build config: %v\n", err)
 		os.Exit(1)
 	}
 
-	dir, err = getBinDir()
+	// create the clientset
+	clientset, err = kubernetes.NewForConfig(config)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to find the directory of this command: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Failed to create client: %v\n", err)
 		os.Exit(1)
 	}
+
+	// OpenShift resources and CSI volume snapshots are got with the dynamic
+	// client, without depending on the OpenShift API and the external snapshotter
+	if openShift || snapshots {
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create dynamic client: %v\n", err)
+			os.Exit(1)
+		}
+		if openShift {
+			fetchOpts.Dynamic = dynamicClient
+		}
+		if snapshots {
+			fetchOpts.SnapshotDynamic = dynamicClient
+		}
+	}
+
+	// test connectivity for k8s cluster and the namespaces
+	for _, ns := range namespaces {
+		_, err = clientset.CoreV1().Namespaces().Get(context.TODO(), ns, metav1.GetOptions{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get namespace %q: %v\n", ns, err)
+			os.Exit(1)
+		}
+	}
 }
 
 func main() {
-	// Get all resources in the namespace
-	res := resources.NewResources(clientset, namespace)
-	g := graph.NewGraph(res, dir)
+	if serve != "" {
+		if err := graphOpts.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid options to serve: %v\n", err)
+			os.Exit(1)
+		}
+		// Durations of --since are relative to each request, not to the start of the server
+		filter := func(res *resources.Resources) error {
+			requestTime, err := parseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+			return filterResources(res, requestTime)
+		}
+		handler := server.NewHandler(clientset, fetchOpts, filter, dir, graphOpts)
+		if err := http.ListenAndServe(serve, handler); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to serve %q: %v\n", serve, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if watch {
+		watchNamespaces()
+		return
+	}
 
-	if outType == "dot" {
-		if err := g.WriteDotFile(outFile); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to output dot file for namespace %q: %v\n", namespace, err)
+	resList := []*resources.Resources{}
+	changesList := []*resources.Changes{}
+	for _, ns := range namespaces {
+		var (
+			res *resources.Resources
+			err error
+		)
+		// Get all resources in the namespace
+		if manifest != "" {
+			res, err = resources.NewResourcesFromManifests(manifest, ns)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read manifests from %q: %v\n", manifest, err)
+				os.Exit(1)
+			}
+		} else {
+			res, err = resources.FetchResourcesWithOptions(context.Background(), clientset, ns, fetchOpts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get resources in namespace %q: %v\n", ns, err)
+				os.Exit(1)
+			}
+		}
+		if err := filterResources(res, sinceTime); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to filter resources in namespace %q: %v\n", ns, err)
 			os.Exit(1)
 		}
-	} else {
-		if err := g.PlotDotFile(outFile, outType); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to output %s file for namespace %q: %v\n", outType, namespace, err)
+		if validate {
+			if errs := res.Validate(); len(errs) > 0 {
+				for _, err := range errs {
+					fmt.Fprintf(os.Stderr, "Invalid reference in namespace %q: %v\n", ns, err)
+				}
+				os.Exit(1)
+			}
+		}
+		resList = append(resList, res)
+
+		// Compare with the old resources, if specified
+		if diff != "" {
+			old, err := resources.NewResourcesFromManifests(diff, ns)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read manifests from %q: %v\n", diff, err)
+				os.Exit(1)
+			}
+			if err := filterResources(old, sinceTime); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to filter resources in namespace %q: %v\n", ns, err)
+				os.Exit(1)
+			}
+			changes, err := resources.Diff(old, res)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to compare resources in namespace %q: %v\n", ns, err)
+				os.Exit(1)
+			}
+			changesList = append(changesList, changes)
+		}
+	}
+
+	if dryRun {
+		warnings, err := graph.DryRun(resList, graphOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate graph for namespace %q: %v\n", namespace, err)
+			os.Exit(1)
+		}
+		for _, w := range warnings {
+			ns := namespace
+			if w.Namespace != "" {
+				ns = w.Namespace
+			}
+			fmt.Fprintf(os.Stderr, "Warning in namespace %q: %s\n", ns, w)
+		}
+		if len(warnings) > 0 {
 			os.Exit(1)
 		}
+		return
+	}
+
+	var (
+		g   *graph.Graph
+		err error
+	)
+	if diff != "" {
+		g, err = graph.NewDiffGraph(changesList, dir, graphOpts)
+	} else {
+		g, err = graph.NewGraphForNamespaces(resList, dir, graphOpts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate graph for namespace %q: %v\n", namespace, err)
+		os.Exit(1)
+	}
+
+	if !writeOutputs(g) {
+		os.Exit(1)
 	}
 }
 
-func getBinDir() (string, error) {
-	s, err := os.Executable()
+// writeOutputs writes the graph to the output files
+// Failures are reported to stderr, and false is returned for them.
+func writeOutputs(g *graph.Graph) bool {
+	plots := []graph.Output{}
+	for i, outType := range outTypes {
+		outFile := outFiles[i]
+		switch outType {
+		case "dot":
+			if err := g.WriteDotFile(outFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to output dot file for namespace %q: %v\n", namespace, err)
+				return false
+			}
+		case "mermaid":
+			if err := g.WriteMermaidFile(outFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to output mermaid file for namespace %q: %v\n", namespace, err)
+				return false
+			}
+		case "json":
+			if err := g.WriteJSONFile(outFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to output json file for namespace %q: %v\n", namespace, err)
+				return false
+			}
+		default:
+			// plotted at once, not to convert the graph to dot format for each file
+			plots = append(plots, graph.Output{File: outFile, Type: outType})
+		}
+	}
+	if len(plots) > 0 {
+		if err := g.PlotDotFiles(plots); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to output files for namespace %q: %v\n", namespace, err)
+			return false
+		}
+	}
+	return true
+}
+
+// watchNamespaces renders the outputs each time resources in the namespaces are changed, until interrupted
+// The outputs are rendered first when the resources in all the namespaces are got.
+// Failures to render are reported to stderr, and the outputs are rendered
+// again on the next changes.
+func watchNamespaces() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var (
+		// mu serializes rendering by the watches of the namespaces
+		mu sync.Mutex
+		// latest is the map of namespace to its latest resources
+		latest = map[string]*resources.Resources{}
+		wg     sync.WaitGroup
+	)
+	for _, ns := range namespaces {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			err := resources.WatchWithOptions(ctx, clientset, ns, fetchOpts, func(res *resources.Resources) {
+				if err := filterResources(res, sinceTime); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to filter resources in namespace %q: %v\n", ns, err)
+					return
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				latest[ns] = res
+				if len(latest) < len(namespaces) {
+					return
+				}
+				resList := []*resources.Resources{}
+				for _, ns := range namespaces {
+					resList = append(resList, latest[ns])
+				}
+				g, err := graph.NewGraphForNamespaces(resList, dir, graphOpts)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to generate graph for namespace %q: %v\n", namespace, err)
+					return
+				}
+				writeOutputs(g)
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to watch namespace %q: %v\n", ns, err)
+			}
+		}(ns)
+	}
+	wg.Wait()
+}
+
+// filterResources filters res by --selector, --name-regex and the time of --since
+func filterResources(res *resources.Resources, sinceTime time.Time) error {
+	if err := res.FilterByLabelSelector(selector); err != nil {
+		return err
+	}
+	if err := res.FilterByName(nameRegex); err != nil {
+		return err
+	}
+	res.FilterByCreationTimestamp(sinceTime)
+	return nil
+}
+
+// compatArgs returns args with the long flags of single dash, like -namespace, replaced by the ones of double dashes
+// Flags were parsed by flag package, which accepts both of them. Shorthands,
+// like -n, the values of flags, like -web of --name-regex, and the
+// arguments after "--" are kept.
+// ex) [-namespace default --name-regex -web] -> [--namespace default --name-regex -web]
+func compatArgs(flags *pflag.FlagSet, args []string) []string {
+	compat := []string{}
+	// isValue is true if the argument is the value of the previous flag
+	isValue := false
+	for i, arg := range args {
+		if isValue {
+			compat = append(compat, arg)
+			isValue = false
+			continue
+		}
+		if arg == "--" {
+			return append(compat, args[i:]...)
+		}
+
+		var flag *pflag.Flag
+		// inline is true if the value is in the same argument, like --namespace=default or -ndefault
+		inline := strings.Contains(arg, "=")
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			flag = flags.Lookup(strings.SplitN(arg[2:], "=", 2)[0])
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			name := strings.SplitN(arg[1:], "=", 2)[0]
+			if len(name) > 1 && flags.Lookup(name) != nil {
+				flag = flags.Lookup(name)
+				arg = "-" + arg
+			} else if name != "" {
+				flag = flags.ShorthandLookup(name[:1])
+				inline = len(arg) > 2
+			}
+		}
+		// Flags without default values for no option take the next argument as their values, unlike bool flags
+		isValue = flag != nil && flag.NoOptDefVal == "" && !inline
+		compat = append(compat, arg)
+	}
+	return compat
+}
+
+// splitList returns the list of comma separated values in s
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// splitRanks returns the list of ranks in s, like "ing;svc;deploy,sts"
+// Each rank in the list has the resource types separated by space, like "deploy sts".
+func splitRanks(s string) []string {
+	ranks := []string{}
+	for _, r := range strings.Split(s, ";") {
+		if types := splitList(r); len(types) > 0 {
+			ranks = append(ranks, strings.Join(types, " "))
+		}
+	}
+	return ranks
+}
+
+// parseSince returns the time that s represents
+// s is a duration before now, like "1h", or a time in RFC3339, like "2021-06-01T00:00:00Z".
+// Zero time is returned for empty s.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return "", err
+		return time.Time{}, fmt.Errorf("must be a duration like 1h or a time like 2021-06-01T00:00:00Z")
+	}
+	return t, nil
+}
+
+// splitMap returns the map of comma separated key=value pairs in s
+func splitMap(s string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, v := range splitList(s) {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid pair %q, must be key=value", v)
+		}
+		m[kv[0]] = kv[1]
+	}
+	return m, nil
+}
+
+// uniqueList returns list without duplicated values, keeping the order
+func uniqueList(list []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, v := range list {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
 	}
-	return filepath.Dir(s), nil
+	return unique
 }
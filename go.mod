@@ -1,15 +1,15 @@
 module github.com/mkimuram/k8sviz
 
-go 1.13
+go 1.16
 
 require (
 	github.com/awalterschulze/gographviz v0.0.0-20190522210029-fa59802746ab
-	github.com/gophercloud/gophercloud v0.9.0 // indirect
 	github.com/imdario/mergo v0.3.8 // indirect
+	github.com/spf13/pflag v1.0.5
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d // indirect
-	golang.org/x/time v0.0.0-20191024005414-555d28b269f0 // indirect
-	k8s.io/api v0.17.4
-	k8s.io/apimachinery v0.17.4
-	k8s.io/client-go v0.17.0
-	k8s.io/utils v0.0.0-20200318093247-d1ab8797c558 // indirect
+	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
+	k8s.io/api v0.19.16
+	k8s.io/apimachinery v0.19.16
+	k8s.io/cli-runtime v0.19.16
+	k8s.io/client-go v0.19.16
 )
@@ -0,0 +1,11 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package icons provides the icons of k8s resources embedded in the binary
+package icons
+
+import "embed"
+
+// FS has the icon files named {resource}-128.png, like pod-128.png
+//go:embed *.png
+var FS embed.FS
@@ -86,5 +86,5 @@ docker run --network host                        \
   -v ${ABSDIR}:/work                             \
   -v ${KUBECONFIG}:/config:ro                    \
   -it --rm ${CONTAINER_IMG}                      \
-  /k8sviz -kubeconfig /config                    \
+  /k8sviz --kubeconfig /config                   \
   -n ${NAMESPACE} -t ${TYPE} -o /work/${FILENAME}
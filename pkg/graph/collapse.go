@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podGroup represents pods owned by the same controller, shown as a single node
+type podGroup struct {
+	// pod is the name of the first pod in the group, used for the node of the group
+	pod   string
+	owner string
+	count int
+}
+
+// collapsePods groups the pods in res by their controllers
+// Pods in the same group are shown as a node of the first pod in the group,
+// and edges from and to the other pods are drawn from and to the node.
+// Pods without controllers and pods hidden by options aren't grouped.
+func (g *Graph) collapsePods(res *resources.Resources) {
+	groups := map[string]*podGroup{}
+	for _, pod := range res.Pods.Items {
+		ref := metav1.GetControllerOf(&pod)
+		if ref == nil || g.isHiddenPod(res, pod.Name) {
+			continue
+		}
+
+		key := ref.Kind + "/" + ref.Name
+		group, ok := groups[key]
+		if !ok {
+			group = &podGroup{pod: pod.Name, owner: ref.Name}
+			groups[key] = group
+		}
+		group.count++
+		g.podGroups[res.Namespace+"/"+pod.Name] = group
+	}
+}
+
+// collapsedReplicaSetOwner returns the name of the deployment that the replicaset is collapsed into
+// Replicasets are collapsed only with CollapseReplicaSets in options, and only
+// if their controllers are deployments in res. It returns false otherwise,
+// like for orphaned replicasets.
+func (g *Graph) collapsedReplicaSetOwner(res *resources.Resources, name string) (string, bool) {
+	if !g.opts.CollapseReplicaSets {
+		return "", false
+	}
+	rs, ok := res.GetResource("rs", name).(*appsv1.ReplicaSet)
+	if !ok {
+		return "", false
+	}
+	ref := metav1.GetControllerOf(rs)
+	if ref == nil || ref.Kind != "Deployment" || !res.HasResource("deploy", ref.Name) {
+		return "", false
+	}
+	return ref.Name, true
+}
+
+// collapsedPodName returns the name of the pod whose node represents the pod
+// It is the name itself, if the pod isn't grouped.
+func (g *Graph) collapsedPodName(namespace, name string) string {
+	if group, ok := g.podGroups[namespace+"/"+name]; ok {
+		return group.pod
+	}
+	return name
+}
+
+// collapsedPodLabel returns the name shown in the label of the pod node
+// ex) my-replicaset (3)
+// It is the name of the pod, if the pod isn't grouped with others.
+func (g *Graph) collapsedPodLabel(namespace, name string) string {
+	if group, ok := g.podGroups[namespace+"/"+name]; ok && group.count > 1 {
+		return fmt.Sprintf("%s (%d)", group.owner, group.count)
+	}
+	return name
+}
@@ -3,8 +3,67 @@
 
 package graph
 
+import (
+	"regexp"
+)
+
 const (
-	clusterPrefix = "cluster_"
-	rankPrefix    = "rank_"
-	imageSuffix   = "-128.png"
+	clusterPrefix   = "cluster_"
+	rankPrefix      = "rank_"
+	rankDummyPrefix = "dummy_"
+	imageSuffix     = "-128.png"
+	// embeddedIconsDirName is the name of the directory in the cache directory to extract embedded icons
+	// It is also the prefix of the temporary directory used instead without the cache directory.
+	embeddedIconsDirName = "k8sviz-icons"
+
+	defaultRankDir = "TD"
+	defaultLayout  = "dot"
+
+	// stdoutFile is the name of the output file to write to standard output
+	stdoutFile = "-"
+
+	// svgzType is the output type of gzip-compressed svg
+	svgzType = "svgz"
+
+	// Kinds of edges, which represent how k8s resources are related
+	edgeKindOwnerRef          = "owner-reference"
+	edgeKindVolume            = "volume"
+	edgeKindConfig            = "config-reference"
+	edgeKindServiceAccount    = "service-account"
+	edgeKindServiceSelector   = "service-selector"
+	edgeKindEndpoints         = "endpoints"
+	edgeKindIngressBackend    = "ingress-backend"
+	edgeKindIngressClass      = "ingress-class"
+	edgeKindScaleTarget       = "scale-target"
+	edgeKindNetworkPolicy     = "network-policy"
+	edgeKindNetworkPolicyPeer = "network-policy-peer"
+	edgeKindDisruptionBudget  = "disruption-budget"
+	edgeKindVolumeBinding     = "volume-binding"
+	edgeKindStorageClass      = "storage-class"
+	edgeKindSnapshotSource    = "snapshot-source"
+	edgeKindRoleRef           = "role-reference"
+	edgeKindRoleSubject       = "role-subject"
+	edgeKindExternalName      = "external-name"
+	edgeKindNodePlacement     = "node-placement"
+	edgeKindGroup             = "group"
+)
+
+var (
+	// invalidIDChars matches the characters in names of nodes that aren't k8s resources, which can't be used in node ids
+	invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+	// rankDirs is the list of rankdir values accepted by graphviz
+	rankDirs = []string{"TB", "BT", "LR", "RL"}
+
+	// layouts is the list of graphviz layout engines, each of which is a command
+	layouts = []string{"dot", "neato", "fdp", "sfdp", "circo", "twopi", "patchwork", "osage"}
+
+	// splinesValues is the list of splines values accepted by graphviz, which controls how edges are drawn
+	splinesValues = []string{"none", "line", "false", "polyline", "curved", "ortho", "spline", "true"}
+
+	// iconExts is the list of extensions of icon files that graphviz can show in labels
+	iconExts = []string{".png", ".svg", ".jpg", ".jpeg", ".gif"}
+
+	// overlapValues is the list of overlap values accepted by graphviz, which controls how overlapped nodes are removed
+	overlapValues = []string{"true", "false", "scale", "scalexy", "prism", "voronoi", "compress", "vpsc", "ipsep", "ortho", "orthoxy", "orthoyx", "portho", "porthoxy", "porthoyx"}
 )
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+)
+
+// diff keeps the changes of resources with the edges in the old and new snapshots
+type diff struct {
+	// changes has the changes for each namespace
+	changes map[string]*resources.Changes
+	// oldEdges and newEdges have the keys of the edges in the graphs of each snapshot
+	oldEdges map[string]bool
+	newEdges map[string]bool
+}
+
+// NewDiffGraph returns a Graph of the changes of k8s resources in multiple namespaces generated with opts
+// changesList has the changes for each namespace, and the resources in both
+// snapshots are shown. Resources and edges are colored by how they are changed:
+// added ones are green, removed ones are red, and unchanged ones are gray.
+// It returns error if opts isn't valid, changesList is empty or the graph has more nodes than opts.MaxNodes.
+func NewDiffGraph(changesList []*resources.Changes, dir string, opts Options) (*Graph, error) {
+	if len(changesList) == 0 {
+		return nil, fmt.Errorf("no namespace is specified")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Edges of each snapshot are found from the graph of all the resources in it
+	snapshotOpts := opts
+	snapshotOpts.Focus = ""
+	oldList, newList, mergedList := []*resources.Resources{}, []*resources.Resources{}, []*resources.Resources{}
+	for _, c := range changesList {
+		oldList = append(oldList, c.Old)
+		newList = append(newList, c.New)
+		mergedList = append(mergedList, c.Merged)
+	}
+	d := &diff{
+		changes:  map[string]*resources.Changes{},
+		oldEdges: newGraph(oldList, dir, snapshotOpts, ioutil.Discard).edgeKeys(),
+		newEdges: newGraph(newList, dir, snapshotOpts, ioutil.Discard).edgeKeys(),
+	}
+	for _, c := range changesList {
+		d.changes[c.Merged.Namespace] = c
+	}
+
+	g := prepareGraph(mergedList, dir, opts, opts.warningOut())
+	g.diff = d
+	g.generate()
+	if err := g.checkMaxNodes(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// edgeKeys returns the set of the keys of the edges in the graph
+func (g *Graph) edgeKeys() map[string]bool {
+	keys := map[string]bool{}
+	for _, e := range g.edges {
+		keys[e.key()] = true
+	}
+	return keys
+}
+
+// key returns the key of the edge to compare edges in different graphs
+func (e edge) key() string {
+	return e.from + "->" + e.to + ":" + e.kind
+}
+
+// colorChanges colors the nodes and the edges by how they are changed
+// Nodes are drawn with box of the color, like below.
+// ```
+// pod_my_namespace__my_pod [ color=green, label=<...>, penwidth=2, shape=box, style=rounded ];
+// pod_my_namespace__my_pod->pvc_my_namespace__my_pvc[ color=green, dir=none ];
+// ```
+// Edges only in the merged resources, like the ones between a removed
+// resource and an added one, are dropped as they are in neither of snapshots.
+func (g *Graph) colorChanges() {
+	for _, n := range g.nodes {
+		c, ok := g.diff.changes[n.namespace]
+		if !ok {
+			continue
+		}
+		color, ok := g.theme.ChangeColors[c.Status(n.resType, n.name)]
+		if !ok {
+			// Skip node that isn't a k8s resource, like groups
+			continue
+		}
+		if _, ok := n.attrs["shape"]; !ok {
+			n.attrs["shape"] = "box"
+			n.attrs["style"] = "rounded"
+		}
+		n.attrs["color"] = color
+		n.attrs["penwidth"] = "2"
+	}
+
+	edges := []edge{}
+	for _, e := range g.edges {
+		inOld, inNew := g.diff.oldEdges[e.key()], g.diff.newEdges[e.key()]
+		switch {
+		case inOld && inNew:
+			e.attrs["color"] = g.theme.ChangeColors[resources.ChangeUnchanged]
+		case inNew:
+			e.attrs["color"] = g.theme.ChangeColors[resources.ChangeAdded]
+		case inOld:
+			e.attrs["color"] = g.theme.ChangeColors[resources.ChangeRemoved]
+		default:
+			continue
+		}
+		edges = append(edges, e)
+	}
+	g.edges = edges
+}
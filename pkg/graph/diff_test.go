@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+)
+
+// edgeColor returns the color of the edge from the node from to the node to in dot, or empty string if no such edge
+func edgeColor(dot, from, to string) string {
+	m := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(from+"->"+to) + `\[[^\]]*\bcolor=(\w+)`).FindStringSubmatch(dot)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+func TestNewDiffGraph(t *testing.T) {
+	old := resourcesFromManifest(t, "default", `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+spec:
+  selector:
+    app: web
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-1
+  namespace: default
+  labels:
+    app: web
+spec:
+  volumes:
+  - name: config
+    configMap:
+      name: config
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: default
+  labels:
+    app: web
+`)
+	new := resourcesFromManifest(t, "default", `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+spec:
+  selector:
+    app: web
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-2
+  namespace: default
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: default
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: default
+`)
+	changes, err := resources.Diff(old, new)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g, err := NewDiffGraph([]*resources.Changes{changes}, iconsDir, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.WriteDot(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dot := buf.String()
+
+	for id, want := range map[string]string{
+		"svc_default__web":   "gray",
+		"pod_default__db":    "gray",
+		"pod_default__web_1": "red",
+		"pod_default__web_2": "green",
+		"cm_default__config": "green",
+	} {
+		if got := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(id) + ` \[ color=(\w+)`).FindStringSubmatch(dot); got == nil || got[1] != want {
+			t.Errorf("color of node %s = %v, want %s:\n%s", id, got, want, dot)
+		}
+	}
+
+	tests := []struct {
+		from string
+		to   string
+		want string
+	}{
+		{from: "pod_default__db", to: "svc_default__web", want: "gray"},
+		{from: "pod_default__web_1", to: "svc_default__web", want: "red"},
+		{from: "pod_default__web_2", to: "svc_default__web", want: "green"},
+		// The removed pod refers to the added configmap only in the merged resources
+		{from: "pod_default__web_1", to: "cm_default__config"},
+	}
+	for _, tt := range tests {
+		if got := edgeColor(dot, tt.from, tt.to); got != tt.want {
+			t.Errorf("color of edge %s->%s = %q, want %q:\n%s", tt.from, tt.to, got, tt.want, dot)
+		}
+	}
+	if got := countEdges(dot, "pod_default__web_1", "cm_default__config"); got != 0 {
+		t.Errorf("got %d edges from the removed pod to the added configmap, want 0:\n%s", got, dot)
+	}
+}
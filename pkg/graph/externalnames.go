@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// externalHostType is the type of the nodes for the hosts outside the cluster that ExternalName services point to
+	externalHostType = "externalhost"
+)
+
+// externalHost returns the host outside the cluster that the service points to
+// ok is false for the services other than ExternalName, and for the ones
+// pointing to services in the cluster, which are connected to the services.
+func externalHost(svc *corev1.Service) (host string, ok bool) {
+	if svc.Spec.Type != corev1.ServiceTypeExternalName || svc.Spec.ExternalName == "" {
+		return "", false
+	}
+	if _, _, inCluster := serviceOfExternalName(svc.Spec.ExternalName); inCluster {
+		return "", false
+	}
+	return svc.Spec.ExternalName, true
+}
+
+// generateExternalHostNodes creates the nodes for the hosts outside the cluster that ExternalName services in res point to
+// ```
+// externalhost_my_namespace__db_example_com [ color=gray, label="external: db.example.com", shape=box, style="rounded,dashed" ];
+// ```
+// They are placed in the same rank as services, with rounded dashed gray box.
+func (g *Graph) generateExternalHostNodes(res *resources.Resources) {
+	rank := g.rankOf("svc")
+	for i := range res.Svcs.Items {
+		host, ok := externalHost(&res.Svcs.Items[i])
+		if !ok {
+			continue
+		}
+		id := g.externalHostName(res.Namespace, host)
+		if g.hasNode[id] {
+			continue
+		}
+
+		attrs := g.theme.ExternalHost.attrs(map[string]string{"label": fmt.Sprintf("%q", "external: "+host), "shape": "box"})
+		g.nodes = append(g.nodes, node{id: id, namespace: res.Namespace, resType: externalHostType, name: host, rank: g.rankName(res.Namespace, rank), attrs: attrs})
+		g.hasNode[id] = true
+	}
+}
+
+// genExternalHostSvcRef generates the edges of ExternalName Service to the host outside the cluster
+func (g *Graph) genExternalHostSvcRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Service.spec.externalName, like db.example.com, that isn't a service in the cluster
+	// ```
+	// externalhost_my_namespace__db_example_com->svc_my_namespace__my_external_service[ dir=back ];
+	// ```
+	for i := range res.Svcs.Items {
+		svc := &res.Svcs.Items[i]
+		host, ok := externalHost(svc)
+		if !ok {
+			continue
+		}
+		g.addEdge(edgeKindExternalName, g.externalHostName(res.Namespace, host), g.resourceName(res.Namespace, "svc", svc.Name), g.theme.ExternalName.attrs(map[string]string{"dir": "back"}))
+	}
+}
+
+// externalHostName returns the id of the node for the host outside the cluster
+// Hosts are shared by the services in the same namespace that point to them.
+func (g *Graph) externalHostName(namespace, host string) string {
+	return externalHostType + "_" + g.escapeName(namespace) + "__" + invalidIDChars.ReplaceAllString(host, "_")
+}
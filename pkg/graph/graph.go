@@ -4,325 +4,1930 @@
 package graph
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/awalterschulze/gographviz"
 	"github.com/mkimuram/k8sviz/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // Graph represents a graph of k8s resources
 type Graph struct {
-	dir  string
-	res  *resources.Resources
-	gviz *gographviz.Graph
+	dir string
+	// resList has the resources for each namespace in the graph
+	resList []*resources.Resources
+	opts    Options
+	gviz    *gographviz.Graph
+	// nodes and edges keep what are added to gviz for k8s resources,
+	// to allow generating outputs other than dot
+	nodes []node
+	edges []edge
+	// hasNode keeps ids of the nodes to skip edges to resources without nodes
+	hasNode map[string]bool
+	// hasEdge keeps keys of the edges to skip duplicated edges
+	hasEdge map[string]bool
+	// icons keeps the icon files in options that exist
+	icons map[string]string
+	// warnings keeps the warnings on generating the graph, which are also written to warnOut
+	warnings []Warning
+	warnOut  io.Writer
+	// podGroups keeps the groups of pods collapsed by options, keyed by "namespace/name" of pods
+	podGroups map[string]*podGroup
+	// podTemplates keeps the ghost pods made from the templates of workloads by options, keyed by "namespace/name" of pods
+	podTemplates map[string]bool
+	// podIndexes keeps the indices of pods by their labels for each namespace, to match selectors
+	podIndexes map[string]*podIndex
+	// theme is the appearance of nodes and edges, which is the default one if not specified by options
+	theme *Theme
+	// diff keeps the changes of resources, only for the graph of the changes
+	diff *diff
+}
+
+// node represents a k8s resource shown as a node of the graph
+type node struct {
+	id        string
+	namespace string
+	resType   string
+	name      string
+	// rank is the name of the rank subgraph that the node is placed in
+	rank  string
+	attrs map[string]string
+}
+
+// edge represents a relation between k8s resources shown as an edge of the graph
+type edge struct {
+	// kind is the kind of the relation, like "owner-reference"
+	kind  string
+	from  string
+	to    string
+	attrs map[string]string
 }
 
 // NewGraph returns a Graph of k8s resources
+// dir is the directory that has icons directory, and the icons embedded in
+// the binary are used if dir is empty.
 func NewGraph(res *resources.Resources, dir string) *Graph {
-	g := &Graph{res: res, dir: dir, gviz: gographviz.NewGraph()}
+	return newGraph([]*resources.Resources{res}, dir, Options{}, os.Stderr)
+}
+
+// NewGraphWithOptions returns a Graph of k8s resources generated with opts
+// It returns error if opts isn't valid or the graph has more nodes than opts.MaxNodes.
+func NewGraphWithOptions(res *resources.Resources, dir string, opts Options) (*Graph, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := newGraph([]*resources.Resources{res}, dir, opts, opts.warningOut())
+	if err := g.checkMaxNodes(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// NewGraphForNamespaces returns a Graph of k8s resources in multiple namespaces generated with opts
+// resList has the resources for each namespace, and each namespace is shown as a cluster.
+// It returns error if opts isn't valid, resList is empty or the graph has more nodes than opts.MaxNodes.
+func NewGraphForNamespaces(resList []*resources.Resources, dir string, opts Options) (*Graph, error) {
+	if len(resList) == 0 {
+		return nil, fmt.Errorf("no namespace is specified")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := newGraph(resList, dir, opts, opts.warningOut())
+	if err := g.checkMaxNodes(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// DryRun generates the graph of k8s resources in multiple namespaces without any output
+// It returns the warnings found on generating the graph, like references to the resources
+// that aren't found, instead of writing them to opts.WarningOut.
+// It returns error if opts isn't valid, resList is empty or the graph has more nodes than opts.MaxNodes.
+func DryRun(resList []*resources.Resources, opts Options) ([]Warning, error) {
+	if len(resList) == 0 {
+		return nil, fmt.Errorf("no namespace is specified")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := newGraph(resList, "", opts, ioutil.Discard)
+	if err := g.checkMaxNodes(); err != nil {
+		return nil, err
+	}
+	return g.Warnings(), nil
+}
+
+// newGraph returns a Graph of k8s resources without validating opts
+// Warnings on generating the graph are written to warnOut.
+func newGraph(resList []*resources.Resources, dir string, opts Options, warnOut io.Writer) *Graph {
+	g := prepareGraph(resList, dir, opts, warnOut)
 	g.generate()
 
 	return g
 }
 
+// checkMaxNodes returns error with the numbers of nodes for each resource type, if the graph has more nodes than opts.MaxNodes
+// The types are listed from the one with the most nodes, to find the ones to
+// filter easily.
+// ex) graph has 1200 nodes, more than the maximum 1000: pod=1000, rs=150, deploy=50
+func (g *Graph) checkMaxNodes() error {
+	if g.opts.MaxNodes == 0 || len(g.nodes) <= g.opts.MaxNodes {
+		return nil
+	}
+
+	counts := map[string]int{}
+	types := []string{}
+	for _, n := range g.nodes {
+		if counts[n.resType] == 0 {
+			types = append(types, n.resType)
+		}
+		counts[n.resType]++
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	list := []string{}
+	for _, t := range types {
+		list = append(list, fmt.Sprintf("%s=%d", t, counts[t]))
+	}
+
+	return fmt.Errorf("graph has %d nodes, more than the maximum %d: %s", len(g.nodes), g.opts.MaxNodes, strings.Join(list, ", "))
+}
+
+// prepareGraph returns a Graph of k8s resources that isn't generated yet
+func prepareGraph(resList []*resources.Resources, dir string, opts Options, warnOut io.Writer) *Graph {
+	if dir == "" {
+		dir = embeddedDir()
+	}
+	g := &Graph{resList: resList, dir: dir, opts: opts, gviz: gographviz.NewGraph(), hasNode: map[string]bool{}, hasEdge: map[string]bool{}, icons: map[string]string{}, podGroups: map[string]*podGroup{}, podTemplates: map[string]bool{}, podIndexes: map[string]*podIndex{}, theme: opts.theme(), warnOut: warnOut}
+	// Check icons in the sorted order for warnings to be reproducible
+	iconTypes := []string{}
+	for resType := range opts.Icons {
+		iconTypes = append(iconTypes, resType)
+	}
+	sort.Strings(iconTypes)
+	for _, resType := range iconTypes {
+		path := opts.Icons[resType]
+		if _, err := os.Stat(path); err != nil {
+			g.warnf("icon %s for %s not found, using the default icon: %v", path, resType, err)
+			continue
+		}
+		g.icons[resType] = path
+	}
+	if opts.ShowPodTemplates {
+		// Not to change resList of the caller
+		g.resList = []*resources.Resources{}
+		for _, res := range resList {
+			g.resList = append(g.resList, g.withPodTemplates(res))
+		}
+	}
+	if opts.CollapsePods {
+		for _, res := range g.resList {
+			g.collapsePods(res)
+		}
+	}
+
+	return g
+}
+
+// GenerateDot returns the graph of k8s resources with dot format
+// The namespace of the graph is the one in res.
+func GenerateDot(res *resources.Resources, dir string) string {
+	return NewGraph(res, dir).toDot()
+}
+
+// GenerateDotForNamespaces returns the graph of k8s resources in multiple namespaces with dot format
+// The namespaces of the graph are the ones in resList.
+func GenerateDotForNamespaces(resList []*resources.Resources, dir string) string {
+	return newGraph(resList, dir, Options{}, os.Stderr).toDot()
+}
+
 // WriteDotFile writes the graph to outFile with dot format
+// The graph is written to standard output, if outFile is "-".
 func (g *Graph) WriteDotFile(outFile string) error {
-	f, err := os.Create(outFile)
-	if err != nil {
+	return g.WriteDotFileContext(context.Background(), outFile)
+}
+
+// WriteDotFileContext writes the graph to outFile with dot format
+// It returns the error of ctx without writing, if ctx is already done.
+func (g *Graph) WriteDotFileContext(ctx context.Context, outFile string) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	if _, err := f.WriteString(g.toDot()); err != nil {
+	return g.writeFile(outFile, func(w io.Writer) error {
+		return g.WriteDotContext(ctx, w)
+	})
+}
+
+// WriteDot writes the graph to w with dot format
+func (g *Graph) WriteDot(w io.Writer) error {
+	return g.WriteDotContext(context.Background(), w)
+}
+
+// WriteDotContext writes the graph to w with dot format
+// It returns the error of ctx without writing, if ctx is already done.
+func (g *Graph) WriteDotContext(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	return nil
+	_, err := io.WriteString(w, g.toDot())
+	return err
 }
 
 // PlotDotFile plots the graph to outFile with outType format
+// The graph is plotted to standard output, if outFile is "-".
 func (g *Graph) PlotDotFile(outFile, outType string) error {
-	cmd := exec.Command("dot", "-T"+outType, "-o", outFile)
-	cmd.Stdin = strings.NewReader(g.toDot())
-	cmd.Stdout = os.Stdout
+	return g.PlotDotFileContext(context.Background(), outFile, outType)
+}
+
+// PlotDotFileContext plots the graph to outFile with outType format
+// The dot process is killed and the error of ctx is returned, if ctx is done
+// before the process completes.
+// The graph is plotted to a temporary file that is renamed to outFile on
+// success, so outFile isn't left partially written on failures.
+func (g *Graph) PlotDotFileContext(ctx context.Context, outFile, outType string) error {
+	return g.plotFile(ctx, g.toDot(), outFile, outType)
+}
+
+// Output represents a file to plot the graph to with a format
+type Output struct {
+	// File is the file to plot to, and "-" is for standard output
+	File string
+	// Type is the format supported by graphviz, like "png"
+	Type string
+}
+
+// PlotDotFiles plots the graph to each of outputs
+func (g *Graph) PlotDotFiles(outputs []Output) error {
+	return g.PlotDotFilesContext(context.Background(), outputs)
+}
+
+// PlotDotFilesContext plots the graph to each of outputs
+// The graph is converted to dot format only once, and plotted by the layout
+// engine for each of outputs in the same way as PlotDotFileContext.
+// The first error is returned. The outputs after the error are still plotted,
+// if ContinuePlotOnError is set in options.
+func (g *Graph) PlotDotFilesContext(ctx context.Context, outputs []Output) error {
+	dot := g.toDot()
+	var firstErr error
+	for _, out := range outputs {
+		if err := g.plotFile(ctx, dot, out.File, out.Type); err != nil {
+			err = fmt.Errorf("failed to plot %s file %q: %w", out.Type, out.File, err)
+			if !g.opts.ContinuePlotOnError {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// plotFile plots dot to outFile with outType format
+// The graph is plotted to a temporary file that is renamed to outFile on
+// success, so outFile isn't left partially written on failures.
+func (g *Graph) plotFile(ctx context.Context, dot, outFile, outType string) error {
+	if strings.EqualFold(filepath.Ext(g.opts.iconSuffix()), ".svg") && outType != "svg" && outType != svgzType {
+		g.warnf("svg icons may not be shown in %s file %s, as it depends on the plugins of graphviz", outType, outFile)
+	}
+
+	g.progressf("rendering %s file %s...", outType, outFile)
+	if outFile == stdoutFile {
+		return g.plotType(ctx, dot, os.Stdout, outType)
+	}
+
+	if err := g.prepareOutputDir(outFile); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(outFile), "."+filepath.Base(outFile)+".")
+	if err != nil {
+		return outputFileError(outFile, err)
+	}
+	err = g.plotType(ctx, dot, f, outType)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), outFile)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+// PlotDot plots the graph to w with outType format
+func (g *Graph) PlotDot(w io.Writer, outType string) error {
+	return g.PlotDotContext(context.Background(), w, outType)
+}
+
+// PlotDotContext plots the graph to w with outType format
+// The dot process is killed and the error of ctx is returned, if ctx is done
+// before the process completes.
+func (g *Graph) PlotDotContext(ctx context.Context, w io.Writer, outType string) error {
+	g.progressf("rendering %s...", outType)
+	return g.plotType(ctx, g.toDot(), w, outType)
+}
+
+// plotType plots dot to w with outType format
+// svgz is compressed from svg by this tool, instead of graphviz that
+// supports it only if built with zlib.
+func (g *Graph) plotType(ctx context.Context, dot string, w io.Writer, outType string) error {
+	if outType != svgzType {
+		return g.plot(ctx, dot, w, "-T"+outType)
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := g.plot(ctx, dot, zw, "-Tsvg"); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// GraphvizVersion returns the version of graphviz used to plot the graph
+// It is the output of the layout engine with -V option.
+// ex) dot - graphviz version 2.43.0 (0)
+func (g *Graph) GraphvizVersion() (string, error) {
+	out, err := exec.Command(g.opts.layout(), "-V").CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// plot runs the command of the layout engine with args, passing dot as its input
+// Standard output of the command is written to w.
+// It fails before running the command, if the command isn't found, and the
+// version of graphviz is added to the error, if the command fails.
+// The command is killed, if it doesn't complete within the timeout in options.
+func (g *Graph) plot(ctx context.Context, dot string, w io.Writer, args ...string) error {
+	layout := g.opts.layout()
+	if _, err := exec.LookPath(layout); err != nil {
+		return fmt.Errorf("graphviz %q not found in PATH; install graphviz or use WriteDotFile: %w", layout, err)
+	}
+
+	if g.opts.PlotTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.opts.PlotTimeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, layout, args...)
+	cmd.Stdin = strings.NewReader(dot)
+	cmd.Stdout = w
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if errors.Is(ctxErr, context.DeadlineExceeded) && g.opts.PlotTimeout > 0 {
+				return fmt.Errorf("%s didn't complete in %v: %w", layout, g.opts.PlotTimeout, ctxErr)
+			}
+			return ctxErr
+		}
+		if version, verErr := g.GraphvizVersion(); verErr == nil {
+			return fmt.Errorf("%s failed (%s): %w", layout, version, err)
+		}
 		return err
 	}
 
 	return nil
 }
 
-// toDot returns a string representation of the graph with dot format
-func (g *Graph) toDot() string {
-	return g.gviz.String()
+// writeFile creates outFile and writes to it with write
+// write writes to standard output, if outFile is "-".
+func (g *Graph) writeFile(outFile string, write func(w io.Writer) error) error {
+	if outFile == stdoutFile {
+		return write(os.Stdout)
+	}
+
+	if err := g.prepareOutputDir(outFile); err != nil {
+		return err
+	}
+	f, err := os.Create(outFile)
+	if err != nil {
+		return outputFileError(outFile, err)
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// prepareOutputDir checks that the directory of outFile exists
+// The directory is created if it doesn't exist and CreateOutputDir is set
+// in options, instead of failing with the error that tells it is missing.
+func (g *Graph) prepareOutputDir(outFile string) error {
+	dir := filepath.Dir(outFile)
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		if g.opts.CreateOutputDir {
+			return os.MkdirAll(dir, 0755)
+		}
+		return fmt.Errorf("directory %q for output file %q doesn't exist", dir, outFile)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q for output file %q isn't a directory", dir, outFile)
+	}
+
+	return nil
+}
+
+// outputFileError returns the error on creating outFile, which tells the directory isn't writable for permission errors
+func outputFileError(outFile string, err error) error {
+	if os.IsPermission(err) {
+		return fmt.Errorf("directory %q for output file %q isn't writable: %w", filepath.Dir(outFile), outFile, err)
+	}
+	return err
+}
+
+// writeString returns the function for writeFile that writes s
+func writeString(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// toDot returns a string representation of the graph with dot format
+func (g *Graph) toDot() string {
+	return g.gviz.String()
+}
+
+// generate generates the graph of the k8s resources
+// Nodes are generated for all namespaces before edges, so that edges across
+// namespaces can be drawn.
+func (g *Graph) generate() {
+	// generate common part of graph
+	g.generateCommon()
+
+	for _, res := range g.resList {
+		// generate cluster for namespace
+		g.generateCluster(res.Namespace)
+
+		// Put resources as Nodes in each rank of subgraph
+		g.generateNodes(res)
+	}
+
+	for _, res := range g.resList {
+		// Connect resources
+		g.generateEdges(res)
+
+		// Connect resources to their groups
+		if g.opts.GroupAnnotation != "" {
+			g.generateGroups(res)
+		}
+	}
+
+	// Color resources and edges by their changes
+	if g.diff != nil {
+		g.colorChanges()
+	}
+
+	// Drop resources far from the focused resource
+	if g.opts.Focus != "" {
+		g.focus()
+	}
+
+	// Sort the nodes and the edges for all the outputs to be reproducible
+	g.sort()
+
+	// Add the nodes and the edges for resources to graphviz graph
+	for _, n := range g.nodes {
+		g.gviz.AddNode(n.rank, n.id, g.withFont(n.attrs, true))
+	}
+	for _, e := range g.edges {
+		g.gviz.AddEdge(e.from, e.to, true, g.withFont(e.attrs, false))
+	}
+
+	// Explain the edges and the icons
+	if g.opts.ShowLegend {
+		g.generateLegend()
+	}
+}
+
+// sort sorts the nodes by their ids and the edges by the ids of their nodes
+// The outputs don't depend on the order of k8s resources returned by the
+// API server or read from manifests, so they change only if resources change.
+func (g *Graph) sort() {
+	sort.SliceStable(g.nodes, func(i, j int) bool {
+		return g.nodes[i].id < g.nodes[j].id
+	})
+	sort.SliceStable(g.edges, func(i, j int) bool {
+		ei, ej := g.edges[i], g.edges[j]
+		if ei.from != ej.from {
+			return ei.from < ej.from
+		}
+		if ei.to != ej.to {
+			return ei.to < ej.to
+		}
+		return ei.kind < ej.kind
+	})
+}
+
+// focus drops the nodes and the edges that aren't reachable within FocusDepth edges
+// from the resources specified by Focus in options
+// Edges are followed regardless of their directions. The resource is looked
+// up in all the namespaces of the graph.
+func (g *Graph) focus() {
+	parts := strings.SplitN(g.opts.Focus, "/", 2)
+	resType, name := parts[0], parts[1]
+
+	reached := map[string]bool{}
+	current := []string{}
+	for _, res := range g.resList {
+		if id := g.resourceName(res.Namespace, resType, name); g.hasNode[id] && !reached[id] {
+			reached[id] = true
+			current = append(current, id)
+		}
+	}
+	if len(current) == 0 {
+		g.resourceWarnf("", resType, name, "%s %s not found to focus on", resType, name)
+	}
+
+	neighbors := map[string][]string{}
+	for _, e := range g.edges {
+		neighbors[e.from] = append(neighbors[e.from], e.to)
+		neighbors[e.to] = append(neighbors[e.to], e.from)
+	}
+	for depth := 0; depth < g.opts.FocusDepth && len(current) > 0; depth++ {
+		next := []string{}
+		for _, id := range current {
+			for _, n := range neighbors[id] {
+				if !reached[n] {
+					reached[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		current = next
+	}
+
+	nodes := []node{}
+	for _, n := range g.nodes {
+		if reached[n.id] {
+			nodes = append(nodes, n)
+		} else {
+			delete(g.hasNode, n.id)
+		}
+	}
+	edges := []edge{}
+	for _, e := range g.edges {
+		if reached[e.from] && reached[e.to] {
+			edges = append(edges, e)
+		}
+	}
+	g.nodes, g.edges = nodes, edges
+}
+
+// generateCommon generates the common part of the graph
+func (g *Graph) generateCommon() {
+	// Create digraph.
+	// ```
+	// digraph G {
+	//   rankdir=TD;
+	// ```
+	// splines and overlap are also set, only if specified by options.
+	g.gviz.SetDir(true)
+	g.gviz.SetName("G")
+	g.gviz.AddAttr("G", "rankdir", g.opts.rankDir())
+	if g.opts.Splines != "" {
+		g.gviz.AddAttr("G", "splines", g.opts.Splines)
+	}
+	if g.opts.Overlap != "" {
+		g.gviz.AddAttr("G", "overlap", g.opts.Overlap)
+	}
+	// Fonts are also set to the graph for the labels of namespaces, only if specified by options.
+	if g.opts.FontName != "" {
+		g.gviz.AddAttr("G", "fontname", fmt.Sprintf("%q", g.opts.FontName))
+	}
+	if g.opts.FontSize != 0 {
+		g.gviz.AddAttr("G", "fontsize", formatFloat(g.opts.FontSize))
+	}
+	// The title is shown at the top, only if specified by options.
+	// ```
+	//   label="my-context: my-namespace at 2021-06-01T00:00:00Z";
+	//   labelloc=t;
+	// ```
+	if g.opts.Title != "" {
+		g.gviz.AddAttr("G", "label", fmt.Sprintf("%q", g.title(time.Now())))
+		g.gviz.AddAttr("G", "labelloc", "t")
+	}
+	// The background is transparent, only if specified by options, to embed the graph in colored backgrounds.
+	if g.opts.TransparentBackground {
+		g.gviz.AddAttr("G", "bgcolor", "transparent")
+	}
+	// Attributes in options are set at last to override the ones above.
+	// ```
+	//   bgcolor=transparent;
+	//   dpi=150;
+	// ```
+	for k, v := range g.opts.GraphAttrs {
+		g.gviz.AddAttr("G", k, quoteAttr(v))
+	}
+}
+
+// withFont returns attrs with the font and the margin of nodes specified by options
+// Nodes and edges don't inherit the attributes of the graph, so they are set
+// to each of them. The margin is set only for nodes. attrs isn't changed.
+func (g *Graph) withFont(attrs map[string]string, isNode bool) map[string]string {
+	if g.opts.FontName == "" && g.opts.FontSize == 0 && (!isNode || g.opts.NodeMargin == 0) {
+		return attrs
+	}
+
+	out := map[string]string{}
+	for k, v := range attrs {
+		out[k] = v
+	}
+	if g.opts.FontName != "" {
+		out["fontname"] = fmt.Sprintf("%q", g.opts.FontName)
+	}
+	if g.opts.FontSize != 0 {
+		out["fontsize"] = formatFloat(g.opts.FontSize)
+	}
+	if isNode && g.opts.NodeMargin != 0 {
+		out["margin"] = formatFloat(g.opts.NodeMargin)
+	}
+	return out
+}
+
+// formatFloat returns the shortest representation of f for attributes
+// ex) 14, 0.5
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// title returns the title of the graph generated at now
+func (g *Graph) title(now time.Time) string {
+	namespaces := []string{}
+	for _, res := range g.resList {
+		namespaces = append(namespaces, res.Namespace)
+	}
+	return strings.NewReplacer(
+		"{namespaces}", strings.Join(namespaces, ", "),
+		"{context}", g.opts.Context,
+		"{time}", now.Format(time.RFC3339),
+	).Replace(g.opts.Title)
+}
+
+// generateCluster generates the cluster for the namespace and its ranks
+func (g *Graph) generateCluster(namespace string) {
+	// Create subgraph for namespace.
+	// ```
+	// subgraph cluster_my_namespace {
+	//   label=<<TABLE BORDER="0"><TR><TD><IMG SRC="/icons/ns-128.png" /></TD></TR><TR><TD>my-namespace</TD></TR></TABLE>>;
+	//   labeljust=l;
+	//   style=dotted;
+	// ```
+	// Ranks are put directly in the graph instead, if the cluster is omitted by options.
+	parent := "G"
+	if !g.opts.OmitCluster {
+		parent = g.clusterName(namespace)
+		g.gviz.AddSubGraph("G", parent,
+			g.theme.Namespace.attrs(map[string]string{"label": g.clusterLabel(namespace), "labeljust": "l"}))
+	}
+
+	// Create subgraphs for resources to group by rank (repeats #ranks)
+	// ```
+	// subgraph rank_my_namespace_0 {
+	// rank=same;
+	// style=invis;
+	// dummy_my_namespace_0 [ height=0, margin=0, style=invis, width=0 ];
+	// }
+	// ;
+	//
+	// subgraph rank_my_namespace_1 {
+	// rank=same;
+	// style=invis;
+	// dummy_my_namespace_1 [ height=0, margin=0, style=invis, width=0 ];
+	// }
+	// ;
+	// ```
+	for r := 0; r < len(g.opts.ranks()); r++ {
+		g.gviz.AddSubGraph(parent, g.rankName(namespace, r),
+			map[string]string{"rank": "same", "style": "invis"})
+		// Put dummy invisible node to order ranks
+		g.gviz.AddNode(g.rankName(namespace, r), g.rankDummyNodeName(namespace, r),
+			map[string]string{"style": "invis", "height": "0", "width": "0", "margin": "0"})
+	}
+
+	// Order ranks (repeats #ranks)
+	// This will make the layout consistent.
+	// ```
+	// dummy_my_namespace_0->dummy_my_namespace_1[ style=invis ];
+	// dummy_my_namespace_1->dummy_my_namespace_2[ style=invis ];
+	// ```
+	for r := 0; r < len(g.opts.ranks())-1; r++ {
+		// Connect rth node and r+1th dummy node with invisible edge
+		g.gviz.AddEdge(g.rankDummyNodeName(namespace, r), g.rankDummyNodeName(namespace, r+1), true,
+			map[string]string{"style": "invis"})
+	}
+}
+
+// generateNodes generates the nodes of the graph
+// K8s resources are represented as graph nodes in k8sviz.
+func (g *Graph) generateNodes(res *resources.Resources) {
+	// Create graphviz nodes for k8s resources like below.
+	// ```
+	// pod_my_namespace__my_pod [ color=forestgreen, label=<<TABLE BORDER="0"><TR><TD><IMG SRC="/icons/pod-128.png" /></TD></TR><TR><TD>my-pod</TD></TR></TABLE>>, penwidth=1, shape=box, style=rounded ];
+	// ```
+	// Each resource is created in the subgraph of the rank for its resource types,
+	// so that the same resource types are placed in the same rank.
+	// Resource types excluded by options are skipped, but their ranks are kept.
+	// Pods hidden by their phases are also skipped, and so are their edges.
+	// Replicasets collapsed into their deployments are skipped, too.
+	// Nodes don't depend on edges, so resources without any relation, like a
+	// deployment created before its replicasets, are also shown, and so are
+	// the ranks only with such resources.
+	for r, rankRes := range g.opts.ranks() {
+		for _, resType := range strings.Fields(rankRes) {
+			if g.opts.isExcluded(resType) {
+				continue
+			}
+			for _, name := range res.GetResourceNames(resType) {
+				if resType == "pod" && g.isHiddenPod(res, name) {
+					continue
+				}
+				if _, ok := g.collapsedReplicaSetOwner(res, name); resType == "rs" && ok {
+					continue
+				}
+				g.addNode(res, r, resType, name)
+			}
+		}
+	}
+
+	// Volumes that aren't k8s resources, like hostPath
+	if g.opts.ShowExternalVolumes {
+		g.generateVolumeNodes(res)
+	}
+
+	// Hosts outside the cluster that ExternalName services point to
+	if g.opts.ShowExternalHosts {
+		g.generateExternalHostNodes(res)
+	}
+}
+
+// isHiddenPod checks if the pod is hidden by options for its phase
+func (g *Graph) isHiddenPod(res *resources.Resources, name string) bool {
+	pod, ok := res.GetResource("pod", name).(*corev1.Pod)
+	if !ok {
+		return false
+	}
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return g.opts.HideSucceededPods
+	case corev1.PodFailed:
+		return g.opts.HideFailedPods
+	}
+	return false
+}
+
+// progressf writes the message of the progress to the writer in options, if specified
+func (g *Graph) progressf(format string, args ...interface{}) {
+	if g.opts.Progress != nil {
+		fmt.Fprintf(g.opts.Progress, format+"\n", args...)
+	}
+}
+
+// addNode adds the node for the k8s resource to the subgraph of the rank
+// Cluster-scoped resources are added only once, to the first namespace having them.
+func (g *Graph) addNode(res *resources.Resources, rank int, resType, name string) {
+	id := g.resourceName(res.Namespace, resType, name)
+	if g.hasNode[id] {
+		return
+	}
+	attrs := map[string]string{"label": g.nodeLabel(res, resType, name), "penwidth": "0"}
+	if color, ok := g.theme.TypeColors[resType]; ok && !g.opts.DisableTypeColor {
+		// Mark resources with the box of the color for the type, which is
+		// overridden by the boxes below except for the color
+		attrs["shape"] = "box"
+		attrs["penwidth"] = "1"
+		attrs = Style{Color: color, Style: "rounded"}.attrs(attrs)
+	}
+	if resources.IsClusterScoped(resType) {
+		// Mark cluster-scoped resources with dashed box
+		attrs["shape"] = "box"
+		attrs["penwidth"] = "1"
+		attrs = g.theme.ClusterScoped.attrs(attrs)
+	}
+	if resType == "svc" && isHeadlessService(res, name) {
+		// Mark headless services with dotted box, to distinguish them from the ones with cluster IP
+		attrs["shape"] = "box"
+		attrs["penwidth"] = "1"
+		attrs = g.theme.HeadlessService.attrs(attrs)
+	}
+	if resType == "rs" && isOrphanedReplicaSet(res, name) {
+		// Mark orphaned replicasets with dashed orange box, as they may be left by incomplete cleanups
+		attrs["shape"] = "box"
+		attrs["penwidth"] = "1"
+		attrs = g.theme.OrphanedReplicaSet.attrs(attrs)
+	}
+	if resType == "pod" && g.isPodTemplate(res.Namespace, name) {
+		// Mark ghost pods made from the templates of workloads with dashed gray box, as they don't exist
+		attrs["shape"] = "box"
+		attrs["penwidth"] = "1"
+		attrs = g.theme.PodTemplate.attrs(attrs)
+	}
+	if g.opts.URLTemplate != "" {
+		attrs["URL"] = fmt.Sprintf("%q", g.nodeURL(res.Namespace, resType, name))
+	}
+	if g.opts.ShowTooltips {
+		attrs["tooltip"] = fmt.Sprintf("%q", g.tooltip(res.GetResource(resType, name), resType, name))
+	}
+	g.nodes = append(g.nodes, node{id: id, namespace: res.Namespace, resType: resType, name: name, rank: g.rankName(res.Namespace, rank), attrs: attrs})
+	g.hasNode[id] = true
+}
+
+// isHeadlessService returns true if the service is headless, which has "None" as cluster IP
+func isHeadlessService(res *resources.Resources, name string) bool {
+	svc, ok := res.GetResource("svc", name).(*corev1.Service)
+	return ok && svc.Spec.ClusterIP == corev1.ClusterIPNone
+}
+
+// isOrphanedReplicaSet returns true if none of the owners of the replicaset is found, like the deleted deployment
+// Replicasets without owners are also orphaned, as deleting deployments
+// with orphan propagation removes the owner references. Replicasets owned by
+// the resource that isn't available for this tool, like CRD, aren't orphaned.
+func isOrphanedReplicaSet(res *resources.Resources, name string) bool {
+	rs, ok := res.GetResource("rs", name).(*appsv1.ReplicaSet)
+	if !ok {
+		return false
+	}
+	for _, ref := range rs.GetOwnerReferences() {
+		ownerKind, err := resources.NormalizeResource(ref.Kind)
+		if err != nil || res.HasResource(ownerKind, ref.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// addEdge adds the directed edge of the kind between the nodes of k8s resources
+// The edge is skipped if either of the nodes isn't added, like the ones for
+// excluded resource types, or if the same edge is already added, like the
+// ones for the same owner referenced twice.
+func (g *Graph) addEdge(kind, from, to string, attrs map[string]string) {
+	if !g.hasNode[from] || !g.hasNode[to] {
+		return
+	}
+	key := from + "->" + to + ":" + attrs["style"]
+	if g.hasEdge[key] {
+		return
+	}
+	g.hasEdge[key] = true
+	g.edges = append(g.edges, edge{kind: kind, from: from, to: to, attrs: attrs})
+}
+
+// generateEdges generates the edges of the graph
+// Relations between k8s resources are represented as graph edges in k8sviz.
+func (g *Graph) generateEdges(res *resources.Resources) {
+	// Owner reference for pod
+	g.genPodOwnerRef(res)
+
+	// Owner reference for rs
+	g.genRsOwnerRef(res)
+
+	// Owner reference for rc
+	g.genRcOwnerRef(res)
+
+	// Owner reference for job
+	g.genJobOwnerRef(res)
+
+	// pvc and pod
+	g.genPvcPodRef(res)
+
+	// pod and volumes that aren't k8s resources
+	if g.opts.ShowExternalVolumes {
+		g.genExternalVolumePodRef(res)
+	}
+
+	// pod and node
+	if g.opts.ShowNodes {
+		g.genPodNodeRef(res)
+	}
+
+	// pvc and pv
+	g.genPvcPvRef(res)
+
+	// pv (or pvc) and storageclass
+	g.genStorageClassRef(res)
+
+	// vs and its source pvc and vsc
+	g.genVolumeSnapshotRef(res)
+
+	// cm and pod
+	g.genCmPodRef(res)
+
+	// secret and pod
+	g.genSecretPodRef(res)
+
+	// sa and pod
+	g.genSaPodRef(res)
+
+	// rolebinding and its role and subjects
+	g.genRoleBindingRef(res)
+
+	// netpol and pod
+	g.genNetpolPodRef(res)
+
+	// pdb and pod
+	g.genPdbPodRef(res)
+
+	// svc and pod
+	if g.opts.UseEndpoints {
+		g.genSvcPodRefByEndpoints(res)
+	} else {
+		g.genSvcPodRef(res)
+	}
+
+	// ingress and svc
+	g.genIngSvcRef(res)
+
+	// route and svc
+	g.genRouteSvcRef(res)
+
+	// ExternalName svc and svc in other namespace
+	g.genExternalNameSvcRef(res)
+
+	// ExternalName svc and host outside the cluster
+	if g.opts.ShowExternalHosts {
+		g.genExternalHostSvcRef(res)
+	}
+
+	// hpa and its scale target
+	g.genHpaTargetRef(res)
+
+	// ingress and ingressclass
+	g.genIngIngressClassRef(res)
+}
+
+// genPodOwnerRef generates the edges of OwnerReferences from Pod
+func (g *Graph) genPodOwnerRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Pod.metadata.ownerReferences.
+	//     - kind
+	//     - name
+	//   - {kind}.metadata.{name}
+	// ```
+	// rs_my_namespace__my_replicaset->pod_my_namespace__my_pod [ style=dashed ];
+	// ```
+	for _, pod := range res.Pods.Items {
+		g.genOwnerRef(res, "pod", pod.Name, pod.GetOwnerReferences())
+	}
+}
+
+// genRsOwnerRef generates the edges of OwnerReferences from RS
+func (g *Graph) genRsOwnerRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - apps/v1.ReplicaSet.metadata.ownerReferences.
+	//     - kind
+	//     - name
+	//   - {kind}.metadata.{name}
+	// ```
+	// deploy_my_namespace__my_deployment->rs_my_namespace__my_replicaset[ style=dashed ];
+	// ```
+	for _, rs := range res.Rss.Items {
+		g.genOwnerRef(res, "rs", rs.Name, rs.GetOwnerReferences())
+	}
+}
+
+// genRcOwnerRef generates the edges of OwnerReferences from RC
+func (g *Graph) genRcOwnerRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.ReplicationController.metadata.ownerReferences.
+	//     - kind
+	//     - name
+	//   - {kind}.metadata.{name}
+	// ```
+	// dc_my_namespace__my_deploymentconfig->rc_my_namespace__my_replicationcontroller[ style=dashed ];
+	// ```
+	for _, rc := range res.Rcs.Items {
+		g.genOwnerRef(res, "rc", rc.Name, rc.GetOwnerReferences())
+	}
+}
+
+// genJobOwnerRef generates the edges of OwnerReferences from Job
+func (g *Graph) genJobOwnerRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - batch/v1.Job.metadata.ownerReferences.
+	//     - kind
+	//     - name
+	//   - {kind}.metadata.{name}
+	// ```
+	// cronjob_my_namespace__my_cronjob->job_my_namespace__my_job[ style=dashed ];
+	// ```
+	// Pods of completed jobs may already be garbage-collected, then only
+	// this edge is drawn, as genPodOwnerRef finds no pods owned by the job.
+	for _, job := range res.Jobs.Items {
+		g.genOwnerRef(res, "job", job.Name, job.GetOwnerReferences())
+	}
+}
+
+// genOwnerRef generates the edges of the owner references of the resource
+// Only the owner with controller: true, which manages the resource, is
+// connected with dashed edge. Other owners are connected with dotted gray
+// edges, only if enabled by options.
+// ```
+// rs_my_namespace__my_replicaset->pod_my_namespace__my_pod [ style=dashed ];
+// deploy_my_namespace__my_other_owner->pod_my_namespace__my_pod [ color=gray, style=dotted ];
+// ```
+func (g *Graph) genOwnerRef(res *resources.Resources, resType, name string, refs []metav1.OwnerReference) {
+	for _, ref := range refs {
+		isController := ref.Controller != nil && *ref.Controller
+		if !isController && !g.opts.ShowNonControllerOwners {
+			continue
+		}
+		ownerKind, err := resources.NormalizeResource(ref.Kind)
+		if err != nil {
+			// Skip resource that isn't available for this tool, like CRD
+			continue
+		}
+		if !res.HasResource(ownerKind, ref.Name) {
+			g.resourceWarnf(res.Namespace, resType, name, "%s %s not found as a owner refernce for %s %s", ownerKind, ref.Name, resType, name)
+			continue
+		}
+		ownerName := ref.Name
+		if ownerKind == "rs" {
+			// Draw the edge from the deployment, if the replicaset isn't shown
+			if deploy, ok := g.collapsedReplicaSetOwner(res, ref.Name); ok {
+				ownerKind, ownerName = "deploy", deploy
+			}
+		}
+
+		attrs := g.theme.OwnerReference.attrs(nil)
+		if !isController {
+			attrs = g.theme.NonControllerOwnerReference.attrs(nil)
+		}
+		g.addEdge(edgeKindOwnerRef, g.resourceName(res.Namespace, ownerKind, ownerName), g.resourceName(res.Namespace, resType, name), attrs)
+	}
+}
+
+// genPvcPodRef generates the edges of PVC to Pod reference
+func (g *Graph) genPvcPodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Pod.spec.volumes[].persistentVolumeClaim.claimName
+	//   - v1.PersistentVolumeClaim.metadata.name
+	// ```
+	// pod_my_namespace__my_pod->pvc_my_namespace__my_persistentvolumeclaim[ dir=none ];
+	// ```
+	for _, pod := range res.Pods.Items {
+		for _, vol := range pod.Spec.Volumes {
+			if vol.VolumeSource.PersistentVolumeClaim != nil {
+				if !res.HasResource("pvc", vol.VolumeSource.PersistentVolumeClaim.ClaimName) {
+					g.resourceWarnf(res.Namespace, "pod", pod.Name, "pvc %s not found as a volume for pod %s", vol.VolumeSource.PersistentVolumeClaim.ClaimName, pod.Name)
+					continue
+				}
+
+				g.addEdge(edgeKindVolume, g.resourceName(res.Namespace, "pod", pod.Name), g.resourceName(res.Namespace, "pvc", vol.VolumeSource.PersistentVolumeClaim.ClaimName),
+					g.theme.Volume.attrs(map[string]string{"dir": "none"}))
+			}
+		}
+	}
+}
+
+// genPodNodeRef generates the edges of Pod to Node that the pod runs on
+func (g *Graph) genPodNodeRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Pod.spec.nodeName
+	//   - v1.Node.metadata.name
+	// ```
+	// pod_my_namespace__my_pod->node_my_node[ color=gray ];
+	// ```
+	for _, pod := range res.Pods.Items {
+		name := pod.Spec.NodeName
+		if name == "" {
+			// Not scheduled yet
+			continue
+		}
+		if !res.HasResource("node", name) {
+			g.resourceWarnf(res.Namespace, "pod", pod.Name, "node %s not found as the node that pod %s runs on", name, pod.Name)
+			continue
+		}
+
+		g.addEdge(edgeKindNodePlacement, g.resourceName(res.Namespace, "pod", pod.Name), g.resourceName(res.Namespace, "node", name), g.theme.NodePlacement.attrs(nil))
+	}
+}
+
+// genPvcPvRef generates the edges of PVC to PV reference
+func (g *Graph) genPvcPvRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.PersistentVolumeClaim.spec.volumeName
+	//   - v1.PersistentVolume.metadata.name
+	// ```
+	// pvc_my_namespace__my_persistentvolumeclaim->pv_my_persistentvolume;
+	// ```
+	for _, pvc := range res.Pvcs.Items {
+		if pvc.Spec.VolumeName == "" {
+			continue
+		}
+		if !res.HasResource("pv", pvc.Spec.VolumeName) {
+			g.resourceWarnf(res.Namespace, "pvc", pvc.Name, "pv %s not found as a volume for pvc %s", pvc.Spec.VolumeName, pvc.Name)
+			continue
+		}
+
+		g.addEdge(edgeKindVolumeBinding, g.resourceName(res.Namespace, "pvc", pvc.Name), g.resourceName(res.Namespace, "pv", pvc.Spec.VolumeName), g.theme.Reference.attrs(nil))
+	}
+}
+
+// genVolumeSnapshotRef generates the edges of VolumeSnapshot to the PVC that it is taken from and the VolumeSnapshotContent bound to it
+func (g *Graph) genVolumeSnapshotRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - snapshot.storage.k8s.io/v1.VolumeSnapshot.spec.source.persistentVolumeClaimName
+	//   - v1.PersistentVolumeClaim.metadata.name
+	// ```
+	// pvc_my_namespace__my_persistentvolumeclaim->vs_my_namespace__my_volumesnapshot[ dir=back ];
+	// ```
+	// and
+	//   - snapshot.storage.k8s.io/v1.VolumeSnapshot.status.boundVolumeSnapshotContentName
+	//     (spec.source.volumeSnapshotContentName, if not bound yet)
+	//   - snapshot.storage.k8s.io/v1.VolumeSnapshotContent.metadata.name
+	// ```
+	// vs_my_namespace__my_volumesnapshot->vsc_my_volumesnapshotcontent;
+	// ```
+	for i := range res.VolumeSnapshots.Items {
+		vs := &res.VolumeSnapshots.Items[i]
+		if name := resources.VolumeSnapshotSource(vs); name != "" {
+			if !res.HasResource("pvc", name) {
+				g.resourceWarnf(res.Namespace, "vs", vs.GetName(), "pvc %s not found as a source for vs %s", name, vs.GetName())
+			} else {
+				g.addEdge(edgeKindSnapshotSource, g.resourceName(res.Namespace, "pvc", name), g.resourceName(res.Namespace, "vs", vs.GetName()), g.theme.Reference.attrs(map[string]string{"dir": "back"}))
+			}
+		}
+		if name := resources.VolumeSnapshotContentName(vs); name != "" {
+			if !res.HasResource("vsc", name) {
+				g.resourceWarnf(res.Namespace, "vs", vs.GetName(), "vsc %s not found as a content for vs %s", name, vs.GetName())
+				continue
+			}
+			g.addEdge(edgeKindVolumeBinding, g.resourceName(res.Namespace, "vs", vs.GetName()), g.resourceName(res.Namespace, "vsc", name), g.theme.Reference.attrs(nil))
+		}
+	}
+}
+
+// genStorageClassRef generates the edges of PV to StorageClass reference
+// The edge is drawn from the PVC instead, if the PVC isn't bound to a PV in the graph.
+func (g *Graph) genStorageClassRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.PersistentVolumeClaim.spec.storageClassName
+	//   - storage.k8s.io/v1.StorageClass.metadata.name
+	// ```
+	// pv_my_persistentvolume->storageclass_my_storageclass;
+	// ```
+	for _, pvc := range res.Pvcs.Items {
+		if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+			continue
+		}
+		name := *pvc.Spec.StorageClassName
+		if !res.HasResource("storageclass", name) {
+			g.resourceWarnf(res.Namespace, "pvc", pvc.Name, "storageclass %s not found for pvc %s", name, pvc.Name)
+			continue
+		}
+
+		from := g.resourceName(res.Namespace, "pvc", pvc.Name)
+		if pvc.Spec.VolumeName != "" && res.HasResource("pv", pvc.Spec.VolumeName) {
+			from = g.resourceName(res.Namespace, "pv", pvc.Spec.VolumeName)
+		}
+		g.addEdge(edgeKindStorageClass, from, g.resourceName(res.Namespace, "storageclass", name), g.theme.Reference.attrs(nil))
+	}
+}
+
+// genCmPodRef generates the edges of ConfigMap to Pod reference
+func (g *Graph) genCmPodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Pod.spec.volumes[].configMap.name
+	//     v1.Pod.spec.containers[].envFrom[].configMapRef.name
+	//     v1.Pod.spec.initContainers[].envFrom[].configMapRef.name
+	//     v1.Pod.spec.containers[].env[].valueFrom.configMapKeyRef.name
+	//     v1.Pod.spec.initContainers[].env[].valueFrom.configMapKeyRef.name
+	//   - v1.ConfigMap.metadata.name
+	// ```
+	// pod_my_namespace__my_pod->cm_my_namespace__my_configmap[ dir=none ];
+	// ```
+	for _, pod := range res.Pods.Items {
+		for _, name := range resources.PodConfigMapNames(&pod) {
+			if !res.HasResource("cm", name) {
+				g.resourceWarnf(res.Namespace, "pod", pod.Name, "cm %s not found as a reference for pod %s", name, pod.Name)
+				continue
+			}
+
+			g.addEdge(edgeKindConfig, g.resourceName(res.Namespace, "pod", pod.Name), g.resourceName(res.Namespace, "cm", name),
+				g.theme.Config.attrs(map[string]string{"dir": "none"}))
+		}
+	}
+}
+
+// genSecretPodRef generates the edges of Secret to Pod reference
+func (g *Graph) genSecretPodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Pod.spec.volumes[].secret.secretName
+	//     v1.Pod.spec.containers[].envFrom[].secretRef.name
+	//     v1.Pod.spec.initContainers[].envFrom[].secretRef.name
+	//     v1.Pod.spec.containers[].env[].valueFrom.secretKeyRef.name
+	//     v1.Pod.spec.initContainers[].env[].valueFrom.secretKeyRef.name
+	//     v1.Pod.spec.imagePullSecrets[].name
+	//   - v1.Secret.metadata.name
+	// ```
+	// pod_my_namespace__my_pod->secret_my_namespace__my_secret[ dir=none ];
+	// ```
+	for _, pod := range res.Pods.Items {
+		for _, name := range resources.PodSecretNames(&pod) {
+			if !res.HasResource("secret", name) {
+				g.resourceWarnf(res.Namespace, "pod", pod.Name, "secret %s not found as a reference for pod %s", name, pod.Name)
+				continue
+			}
+
+			g.addEdge(edgeKindConfig, g.resourceName(res.Namespace, "pod", pod.Name), g.resourceName(res.Namespace, "secret", name),
+				g.theme.Config.attrs(map[string]string{"dir": "none"}))
+		}
+	}
+}
+
+// genSaPodRef generates the edges of ServiceAccount to Pod reference
+func (g *Graph) genSaPodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Pod.spec.serviceAccountName ("default" if empty)
+	//   - v1.ServiceAccount.metadata.name
+	// ```
+	// pod_my_namespace__my_pod->sa_my_namespace__my_serviceaccount[ dir=none, style=dashed ];
+	// ```
+	for _, pod := range res.Pods.Items {
+		name := pod.Spec.ServiceAccountName
+		if name == "" {
+			name = "default"
+		}
+		if !res.HasResource("sa", name) {
+			g.resourceWarnf(res.Namespace, "pod", pod.Name, "sa %s not found as a service account for pod %s", name, pod.Name)
+			continue
+		}
+
+		g.addEdge(edgeKindServiceAccount, g.resourceName(res.Namespace, "pod", pod.Name), g.resourceName(res.Namespace, "sa", name),
+			g.theme.ServiceAccount.attrs(map[string]string{"dir": "none"}))
+	}
 }
 
-// generate generates the graph of the k8s resources
-func (g *Graph) generate() {
-	// generate common part of graph
-	g.generateCommon()
+// genRoleBindingRef generates the edges of RoleBinding to Role and ServiceAccount reference
+func (g *Graph) genRoleBindingRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - rbac.authorization.k8s.io/v1.RoleBinding.roleRef.kind and name
+	//   - rbac.authorization.k8s.io/v1.Role.metadata.name (or ClusterRole)
+	// or
+	//   - rbac.authorization.k8s.io/v1.RoleBinding.subjects[].name of kind ServiceAccount in the namespace
+	//   - v1.ServiceAccount.metadata.name
+	// ```
+	// rolebinding_my_namespace__my_rolebinding->role_my_namespace__my_role;
+	// rolebinding_my_namespace__my_rolebinding->clusterrole_my_clusterrole;
+	// sa_my_namespace__my_serviceaccount->rolebinding_my_namespace__my_rolebinding[ dir=back ];
+	// ```
+	for _, rb := range res.RoleBindings.Items {
+		roleKind, err := resources.NormalizeResource(rb.RoleRef.Kind)
+		if err != nil {
+			// Skip role that isn't available for this tool
+			continue
+		}
+		if !res.HasResource(roleKind, rb.RoleRef.Name) {
+			g.resourceWarnf(res.Namespace, "rolebinding", rb.Name, "%s %s not found as a role for rolebinding %s", roleKind, rb.RoleRef.Name, rb.Name)
+		} else {
+			g.addEdge(edgeKindRoleRef, g.resourceName(res.Namespace, "rolebinding", rb.Name), g.resourceName(res.Namespace, roleKind, rb.RoleRef.Name), g.theme.Reference.attrs(nil))
+		}
 
-	// Put resources as Nodes in each rank of subgraph
-	g.generateNodes()
+		for _, subject := range rb.Subjects {
+			if subject.Kind != rbacv1.ServiceAccountKind || (subject.Namespace != "" && subject.Namespace != res.Namespace) {
+				// Skip users, groups and service accounts in other namespaces, which aren't shown
+				continue
+			}
+			if !res.HasResource("sa", subject.Name) {
+				g.resourceWarnf(res.Namespace, "rolebinding", rb.Name, "sa %s not found as a subject for rolebinding %s", subject.Name, rb.Name)
+				continue
+			}
 
-	// Connect resources
-	g.generateEdges()
+			g.addEdge(edgeKindRoleSubject, g.resourceName(res.Namespace, "sa", subject.Name), g.resourceName(res.Namespace, "rolebinding", rb.Name), g.theme.Reference.attrs(map[string]string{"dir": "back"}))
+		}
+	}
 }
 
-// generateCommon generates the common part of the graph
-func (g *Graph) generateCommon() {
-	// Create digraph for namespace.
+// genSvcPodRef generates the edges of Service to Pod reference
+func (g *Graph) genSvcPodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Service.spec.selector
+	//   - v1.Pod.metadata.labels
 	// ```
-	// digraph G {
-	//   rankdir=TD;
-	//   label=<<TABLE BORDER="0"><TR><TD><IMG SRC="/icons/ns-128.png" /></TD></TR><TR><TD>ns1</TD></TR></TABLE>>;
-	//   labeljust=l;
-	//   style=dotted;
+	// pod_my_namespace__my_pod->svc_my_namespace__my_service[ dir=back ];
 	// ```
-	g.gviz.SetDir(true)
-	g.gviz.SetName("G")
-	g.gviz.AddAttr("G", "rankdir", "TD")
-	g.gviz.AddSubGraph("G", g.clusterName(),
-		map[string]string{"label": g.clusterLabel(), "labeljust": "l", "style": "dotted"})
+	for _, svc := range res.Svcs.Items {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		// Check if pod has all labels specified in svc.Spec.Selector
+		for _, name := range g.matchedPodNames(res, labels.SelectorFromSet(svc.Spec.Selector)) {
+			g.addEdge(edgeKindServiceSelector, g.resourceName(res.Namespace, "pod", name), g.resourceName(res.Namespace, "svc", svc.Name),
+				g.svcPodEdgeAttrs(res, &svc, name))
+		}
+	}
+}
 
-	// Create subgraphs for resources to group by rank (repeats #ResourceTypes)
+// genNetpolPodRef generates the edges of NetworkPolicy to Pod reference
+func (g *Graph) genNetpolPodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - networking.k8s.io/v1.NetworkPolicy.spec.podSelector
+	//   - v1.Pod.metadata.labels
 	// ```
-	// subgraph rank_0 {
-	// rank=same;
-	// style=invis;
-	// 0 [ height=0, margin=0, style=invis, width=0 ];
-	// }
-	// ;
-	//
-	// subgraph rank_1 {
-	// rank=same;
-	// style=invis;
-	// 1 [ height=0, margin=0, style=invis, width=0 ];
-	// }
-	// ;
+	// netpol_my_namespace__my_networkpolicy->pod_my_namespace__my_pod[ color=red ];
 	// ```
-	for r := 0; r < len(resources.ResourceTypes); r++ {
-		g.gviz.AddSubGraph(g.clusterName(), g.rankName(r),
-			map[string]string{"rank": "same", "style": "invis"})
-		// Put dummy invisible node to order ranks
-		g.gviz.AddNode(g.rankName(r), g.rankDummyNodeName(r),
-			map[string]string{"style": "invis", "height": "0", "width": "0", "margin": "0"})
+	// Empty podSelector selects all pods in the namespace.
+	// If enabled by options, pods selected by peers of ingress and egress rules
+	// are also connected, like below.
+	// ```
+	// pod_my_namespace__my_ingress_peer->netpol_my_namespace__my_networkpolicy[ color=red, style=dashed ];
+	// netpol_my_namespace__my_networkpolicy->pod_my_namespace__my_egress_peer[ color=red, style=dashed ];
+	// ```
+	for _, netpol := range res.NetworkPolicies.Items {
+		sel, err := metav1.LabelSelectorAsSelector(&netpol.Spec.PodSelector)
+		if err != nil {
+			g.resourceWarnf(res.Namespace, "netpol", netpol.Name, "invalid podSelector for netpol %s: %v", netpol.Name, err)
+			continue
+		}
+		pods := g.matchedPodNames(res, sel)
+		if len(pods) == 0 {
+			g.resourceWarnf(res.Namespace, "netpol", netpol.Name, "no pod matches podSelector for netpol %s", netpol.Name)
+		}
+		for _, name := range pods {
+			g.addEdge(edgeKindNetworkPolicy, g.resourceName(res.Namespace, "netpol", netpol.Name), g.resourceName(res.Namespace, "pod", name),
+				g.theme.NetworkPolicy.attrs(nil))
+		}
+
+		if !g.opts.ShowNetworkPolicyPeers {
+			continue
+		}
+		for _, rule := range netpol.Spec.Ingress {
+			for _, name := range g.peerPodNames(res, rule.From) {
+				g.addEdge(edgeKindNetworkPolicyPeer, g.resourceName(res.Namespace, "pod", name), g.resourceName(res.Namespace, "netpol", netpol.Name),
+					g.theme.NetworkPolicyPeer.attrs(nil))
+			}
+		}
+		for _, rule := range netpol.Spec.Egress {
+			for _, name := range g.peerPodNames(res, rule.To) {
+				g.addEdge(edgeKindNetworkPolicyPeer, g.resourceName(res.Namespace, "netpol", netpol.Name), g.resourceName(res.Namespace, "pod", name),
+					g.theme.NetworkPolicyPeer.attrs(nil))
+			}
+		}
 	}
+}
 
-	// Order ranks (repeats #ResourceTypes)
-	// This will make the layout consistent.
+// genPdbPodRef generates the edges of PodDisruptionBudget to Pod reference
+func (g *Graph) genPdbPodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - policy/v1beta1.PodDisruptionBudget.spec.selector
+	//   - v1.Pod.metadata.labels
 	// ```
-	// 0->1[ style=invis ];
-	// 1->2[ style=invis ];
+	// pdb_my_namespace__my_pdb->pod_my_namespace__my_pod[ color=purple ];
 	// ```
-	for r := 0; r < len(resources.ResourceTypes)-1; r++ {
-		// Connect rth node and r+1th dummy node with invisible edge
-		g.gviz.AddEdge(g.rankDummyNodeName(r), g.rankDummyNodeName(r+1), true,
-			map[string]string{"style": "invis"})
+	// Empty selector selects all pods in the namespace, and no selector selects no pods.
+	for _, pdb := range res.Pdbs.Items {
+		sel, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			g.resourceWarnf(res.Namespace, "pdb", pdb.Name, "invalid selector for pdb %s: %v", pdb.Name, err)
+			continue
+		}
+		pods := g.matchedPodNames(res, sel)
+		if len(pods) == 0 {
+			g.resourceWarnf(res.Namespace, "pdb", pdb.Name, "no pod matches selector for pdb %s", pdb.Name)
+		}
+		for _, name := range pods {
+			g.addEdge(edgeKindDisruptionBudget, g.resourceName(res.Namespace, "pdb", pdb.Name), g.resourceName(res.Namespace, "pod", name),
+				g.theme.DisruptionBudget.attrs(nil))
+		}
 	}
 }
 
-// generateNodes generates the nodes of the graph
-// K8s resources are represented as graph nodes in k8sviz.
-func (g *Graph) generateNodes() {
-	// Create graphviz nodes for k8s resources like below.
+// peerPodNames returns the names of the pods selected by podSelector of the peers
+// Peers with namespaceSelector or ipBlock are skipped, as they select
+// resources that aren't shown as pods in the namespace.
+func (g *Graph) peerPodNames(res *resources.Resources, peers []networkingv1.NetworkPolicyPeer) []string {
+	names := []string{}
+	for _, peer := range peers {
+		if peer.PodSelector == nil || peer.NamespaceSelector != nil {
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(peer.PodSelector)
+		if err != nil {
+			continue
+		}
+		names = append(names, g.matchedPodNames(res, sel)...)
+	}
+
+	return names
+}
+
+// genSvcPodRefByEndpoints generates the edges of Service to Pod reference from Endpoints
+// Unlike genSvcPodRef, it shows pods that actually back services, including
+// the ones for services without selector and the ones that aren't ready.
+func (g *Graph) genSvcPodRefByEndpoints(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Endpoints.metadata.name (same as v1.Service.metadata.name)
+	//     v1.Endpoints.subsets[].addresses[].targetRef.name
+	//     v1.Endpoints.subsets[].notReadyAddresses[].targetRef.name
+	//   - v1.Pod.metadata.name
 	// ```
-	// pod_my_pod [ label=<<TABLE BORDER="0"><TR><TD><IMG SRC="/icons/pod-128.png" /></TD></TR><TR><TD>my-pod</TD></TR></TABLE>>, penwidth=0 ];
+	// pod_my_namespace__my_pod->svc_my_namespace__my_service[ dir=back ];
 	// ```
-	// Each resource is created in the subgraph of the rank for its resource types,
-	// so that the same resource types are placed in the same rank.
-	for r, rankRes := range resources.ResourceTypes {
-		for _, resType := range strings.Fields(rankRes) {
-			for _, name := range g.res.GetResourceNames(resType) {
-				g.gviz.AddNode(g.rankName(r), g.resourceName(resType, name),
-					map[string]string{"label": g.resourceLabel(resType, name), "penwidth": "0"})
+	// Edges to the pods that aren't ready are drawn with dashed red line, if
+	// enabled by options.
+	// ```
+	// pod_my_namespace__my_pod->svc_my_namespace__my_service[ color=red, dir=back, style=dashed ];
+	// ```
+	for _, ep := range res.Endpoints.Items {
+		if !res.HasResource("svc", ep.Name) {
+			continue
+		}
+
+		ready := endpointReadiness(res, &ep)
+		seen := map[string]bool{}
+		for _, subset := range ep.Subsets {
+			addrs := append(append([]corev1.EndpointAddress{}, subset.Addresses...), subset.NotReadyAddresses...)
+			for _, addr := range addrs {
+				if addr.TargetRef == nil || addr.TargetRef.Kind != "Pod" || seen[addr.TargetRef.Name] {
+					continue
+				}
+				seen[addr.TargetRef.Name] = true
+				if !res.HasResource("pod", addr.TargetRef.Name) {
+					g.resourceWarnf(res.Namespace, "svc", ep.Name, "pod %s not found as an endpoint for svc %s", addr.TargetRef.Name, ep.Name)
+					continue
+				}
+
+				svc, _ := res.GetResource("svc", ep.Name).(*corev1.Service)
+				attrs := g.svcPodEdgeAttrs(res, svc, addr.TargetRef.Name)
+				if g.opts.ShowEndpointReadiness && !ready[addr.TargetRef.Name] {
+					attrs = g.theme.NotReadyEndpoint.attrs(attrs)
+				}
+				g.addEdge(edgeKindEndpoints, g.resourceName(res.Namespace, "pod", addr.TargetRef.Name), g.resourceName(res.Namespace, "svc", ep.Name), attrs)
 			}
 		}
 	}
 }
 
-// generateEdges generates the edges of the graph
-// Relations between k8s resources are represented as graph edges in k8sviz.
-func (g *Graph) generateEdges() {
-	// Owner reference for pod
-	g.genPodOwnerRef()
+// endpointReadiness returns the map of the names of the pods behind the Endpoints to their readiness
+// The readiness is read from conditions.ready of EndpointSlices of the
+// service, and from the addresses of the Endpoints if the service has no
+// EndpointSlice, like the ones read from manifests without EndpointSlice.
+// Endpoints without conditions.ready are handled as ready, as defined in the API.
+func endpointReadiness(res *resources.Resources, ep *corev1.Endpoints) map[string]bool {
+	ready := map[string]bool{}
+	hasSlice := false
+	for _, slice := range res.EndpointSlices.Items {
+		if slice.Labels[discoveryv1beta1.LabelServiceName] != ep.Name {
+			continue
+		}
+		hasSlice = true
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+				continue
+			}
+			ready[endpoint.TargetRef.Name] = endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+		}
+	}
+	if hasSlice {
+		return ready
+	}
 
-	// Owner reference for rs
-	g.genRsOwnerRef()
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+				ready[addr.TargetRef.Name] = true
+			}
+		}
+	}
+	return ready
+}
 
-	// pvc and pod
-	g.genPvcPodRef()
+// svcPodEdgeAttrs returns the attributes of the edge between the service and the pod
+// The ports of the service are set as the label of the edge, if enabled by options.
+// ex) pod_my_namespace__my_pod->svc_my_namespace__my_service[ dir=back, label="80->8080" ];
+func (g *Graph) svcPodEdgeAttrs(res *resources.Resources, svc *corev1.Service, podName string) map[string]string {
+	attrs := g.theme.ServiceSelector.attrs(map[string]string{"dir": "back"})
+	if !g.opts.ShowServicePorts || svc == nil {
+		return attrs
+	}
+	pod, ok := res.GetResource("pod", podName).(*corev1.Pod)
+	if !ok {
+		return attrs
+	}
 
-	// svc and pod
-	g.genSvcPodRef()
+	if ports := servicePorts(svc, pod); len(ports) > 0 {
+		attrs["label"] = fmt.Sprintf("%q", strings.Join(ports, ", "))
+	}
 
-	// ingress and svc
-	g.genIngSvcRef()
+	return attrs
 }
 
-// genPodOwnerRef generates the edges of OwnerReferences from Pod
-func (g *Graph) genPodOwnerRef() {
-	// Add edge if below matches:
-	//   - v1.Pod.metadata.ownerReferences.
-	//     - kind
-	//     - name
-	//   - {kind}.metadata.{name}
-	// ```
-	// rs_my_replicaset->pod_my_pod [ style=dashed ];
-	// ```
-	for _, pod := range g.res.Pods.Items {
-		for _, ref := range pod.GetOwnerReferences() {
-			ownerKind, err := resources.NormalizeResource(ref.Kind)
-			if err != nil {
-				// Skip resource that isn't available for this tool, like CRD
+// servicePorts returns the mappings of the service ports to the container ports of the pod
+// Named target ports are resolved with the container ports of the pod, and
+// the ports whose names aren't found in the pod are skipped.
+// ex) [80->8080 443->8443]
+func servicePorts(svc *corev1.Service, pod *corev1.Pod) []string {
+	ports := []string{}
+	for _, port := range svc.Spec.Ports {
+		target := port.TargetPort.IntVal
+		switch {
+		case port.TargetPort.Type == intstr.String:
+			target = containerPort(pod, port.TargetPort.StrVal)
+			if target == 0 {
 				continue
 			}
-			if !g.res.HasResource(ownerKind, ref.Name) {
-				fmt.Fprintf(os.Stderr, "%s %s not found as a owner refernce for po %s\n", ownerKind, ref.Name, pod.Name)
-				continue
+		case target == 0:
+			// targetPort defaults to the same value as port
+			target = port.Port
+		}
+		ports = append(ports, fmt.Sprintf("%d->%d", port.Port, target))
+	}
+
+	return ports
+}
+
+// containerPort returns the number of the named container port in the pod
+// It returns 0 if no container port has the name.
+func containerPort(pod *corev1.Pod, name string) int32 {
+	for _, c := range pod.Spec.Containers {
+		for _, port := range c.Ports {
+			if port.Name == name {
+				return port.ContainerPort
 			}
-			g.gviz.AddEdge(g.resourceName(ownerKind, ref.Name), g.resourceName("pod", pod.Name), true,
-				map[string]string{"style": "dashed"})
 		}
 	}
+
+	return 0
 }
 
-// genRsOwnerRef generates the edges of OwnerReferences from RS
-func (g *Graph) genRsOwnerRef() {
+// genIngSvcRef generates the edges of Ingress to Service reference
+func (g *Graph) genIngSvcRef(res *resources.Resources) {
 	// Add edge if below matches:
-	//   - apps/v1.ReplicaSet.metadata.ownerReferences.
-	//     - kind
-	//     - name
-	//   - {kind}.metadata.{name}
+	//   - networking.k8s.io/v1.Ingress.spec.defaultBackend.service.name
+	//     networking.k8s.io/v1.Ingress.spec.rules[].http.paths[].backend.service.name
+	//   - v1.Service.metadata.name
 	// ```
-	// deploy_my_deployment->rs_my_replicaset[ style=dashed ];
+	// svc_my_namespace__my_service->ing_my_namespace__my_ingress[ dir=back ];
 	// ```
-	for _, rs := range g.res.Rss.Items {
-		for _, ref := range rs.GetOwnerReferences() {
-			ownerKind, err := resources.NormalizeResource(ref.Kind)
-			if err != nil {
-				// Skip resource that isn't available for this tool, like CRD
+	for _, ing := range res.Ingresses.Items {
+		backends := []networkingv1.IngressBackend{}
+		if ing.Spec.DefaultBackend != nil {
+			backends = append(backends, *ing.Spec.DefaultBackend)
+		}
+		for _, rule := range ing.Spec.Rules {
+			// Rules without http, like the ones only with host, have no backend
+			if rule.IngressRuleValue.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.IngressRuleValue.HTTP.Paths {
+				backends = append(backends, path.Backend)
+			}
+		}
+
+		for _, backend := range backends {
+			if backend.Service == nil {
+				// Skip resource backend, which isn't a service
 				continue
 			}
-			if !g.res.HasResource(ownerKind, ref.Name) {
-				fmt.Fprintf(os.Stderr, "%s %s not found as a owner refernce for rs %s\n", ownerKind, ref.Name, rs.Name)
+			if !res.HasResource("svc", backend.Service.Name) {
+				g.resourceWarnf(res.Namespace, "ing", ing.Name, "svc %s not found for ingress %s", backend.Service.Name, ing.Name)
 				continue
 			}
 
-			g.gviz.AddEdge(g.resourceName(ownerKind, ref.Name), g.resourceName("rs", rs.Name), true,
-				map[string]string{"style": "dashed"})
+			g.addEdge(edgeKindIngressBackend, g.resourceName(res.Namespace, "svc", backend.Service.Name), g.resourceName(res.Namespace, "ing", ing.Name), g.theme.IngressBackend.attrs(map[string]string{"dir": "back"}))
 		}
 	}
 }
 
-// genPvcPodRef generates the edges of PVC to Pod reference
-func (g *Graph) genPvcPodRef() {
+// genRouteSvcRef generates the edges of OpenShift Route to Service reference
+func (g *Graph) genRouteSvcRef(res *resources.Resources) {
 	// Add edge if below matches:
-	//   - v1.Pod.spec.volumes[].persistentVolumeClaim.claimName
-	//   - v1.PersistentVolumeClaim.metadata.name
+	//   - route.openshift.io/v1.Route.spec.to.name
+	//     route.openshift.io/v1.Route.spec.alternateBackends[].name
+	//   - v1.Service.metadata.name
 	// ```
-	// pod_my_pod->pvc_my_persistentvolumeclaim[ dir=none ];
+	// svc_my_namespace__my_service->route_my_namespace__my_route[ dir=back ];
 	// ```
-	for _, pod := range g.res.Pods.Items {
-		for _, vol := range pod.Spec.Volumes {
-			if vol.VolumeSource.PersistentVolumeClaim != nil {
-				if !g.res.HasResource("pvc", vol.VolumeSource.PersistentVolumeClaim.ClaimName) {
-					fmt.Fprintf(os.Stderr, "pvc %s not found as a volume for pod %s\n", vol.VolumeSource.PersistentVolumeClaim.ClaimName, pod.Name)
-					continue
-				}
-
-				g.gviz.AddEdge(g.resourceName("pod", pod.Name), g.resourceName("pvc", vol.VolumeSource.PersistentVolumeClaim.ClaimName), true,
-					map[string]string{"dir": "none"})
+	for i := range res.Routes.Items {
+		route := &res.Routes.Items[i]
+		for _, name := range resources.RouteServiceNames(route) {
+			if !res.HasResource("svc", name) {
+				g.resourceWarnf(res.Namespace, "route", route.GetName(), "svc %s not found for route %s", name, route.GetName())
+				continue
 			}
+
+			g.addEdge(edgeKindIngressBackend, g.resourceName(res.Namespace, "svc", name), g.resourceName(res.Namespace, "route", route.GetName()), g.theme.IngressBackend.attrs(map[string]string{"dir": "back"}))
 		}
 	}
 }
 
-// genSvcPodRef generates the edges of Service to Pod reference
-func (g *Graph) genSvcPodRef() {
+// genExternalNameSvcRef generates the edges of ExternalName Service to the Service in other namespace
+// Ingress can't refer to services in other namespaces directly, so
+// ExternalName services that point to them are used as backends instead.
+// The service is shown as a placeholder node with dashed gray box outside
+// namespaces, if the namespace of the service isn't in the graph.
+func (g *Graph) genExternalNameSvcRef(res *resources.Resources) {
 	// Add edge if below matches:
-	//   - v1.Service.spec.selector
-	//   - v1.Pod.metadata.labels
+	//   - v1.Service.spec.externalName, like my-service.my-other-namespace.svc.cluster.local
+	//   - v1.Service.metadata.name in the namespace
 	// ```
-	// pod_my_pod->svc_my_service[ dir=back ];
+	// svc_my_other_namespace__my_service->svc_my_namespace__my_external_service[ dir=back ];
 	// ```
-	for _, svc := range g.res.Svcs.Items {
-		if len(svc.Spec.Selector) == 0 {
+	for _, svc := range res.Svcs.Items {
+		if svc.Spec.Type != corev1.ServiceTypeExternalName {
+			continue
+		}
+		namespace, name, ok := serviceOfExternalName(svc.Spec.ExternalName)
+		if !ok || namespace == res.Namespace {
 			continue
 		}
-		// Check if pod has all labels specified in svc.Spec.Selector
-		for _, pod := range g.res.Pods.Items {
-			podLabel := pod.GetLabels()
-			matched := true
-			for selKey, selVal := range svc.Spec.Selector {
-				val, ok := podLabel[selKey]
-				if !ok || selVal != val {
-					matched = false
-					break
-				}
-			}
 
-			if matched {
-				g.gviz.AddEdge(g.resourceName("pod", pod.Name), g.resourceName("svc", svc.Name), true,
-					map[string]string{"dir": "back"})
-			}
+		target, fetched := g.resourcesOf(namespace)
+		switch {
+		case !fetched:
+			g.addPlaceholderNode(namespace, "svc", name)
+		case !target.HasResource("svc", name):
+			g.resourceWarnf(res.Namespace, "svc", svc.Name, "svc %s not found in namespace %s as an external name for svc %s", name, namespace, svc.Name)
+			continue
 		}
+
+		g.addEdge(edgeKindExternalName, g.resourceName(namespace, "svc", name), g.resourceName(res.Namespace, "svc", svc.Name), g.theme.ExternalName.attrs(map[string]string{"dir": "back"}))
 	}
 }
 
-// genIngSvcRef generates the edges of Ingress to Service reference
-func (g *Graph) genIngSvcRef() {
+// serviceOfExternalName returns the namespace and the name of the service that externalName points to
+// externalName is like my-service.my-namespace.svc.cluster.local, and the
+// cluster domain after "svc" can be omitted or different.
+// ok is false, if externalName isn't the one of a service in the cluster.
+func serviceOfExternalName(externalName string) (namespace, name string, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(externalName, "."), ".")
+	if len(parts) < 3 || parts[2] != "svc" || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[0], true
+}
+
+// resourcesOf returns the resources in the namespace of the graph
+// ok is false, if the namespace isn't in the graph.
+func (g *Graph) resourcesOf(namespace string) (res *resources.Resources, ok bool) {
+	for _, res := range g.resList {
+		if res.Namespace == namespace {
+			return res, true
+		}
+	}
+	return nil, false
+}
+
+// addPlaceholderNode adds the node of the resource in the namespace that isn't in the graph
+// The node is shown with dashed gray box outside namespaces, like below.
+// ```
+// svc_my_other_namespace__my_service [ color=gray, label="svc my-other-namespace/my-service", shape=box, style=dashed ];
+// ```
+func (g *Graph) addPlaceholderNode(namespace, resType, name string) {
+	id := g.resourceName(namespace, resType, name)
+	if g.hasNode[id] {
+		return
+	}
+
+	attrs := g.theme.Placeholder.attrs(map[string]string{"label": fmt.Sprintf("%q", resType+" "+namespace+"/"+name), "shape": "box"})
+	g.nodes = append(g.nodes, node{id: id, namespace: namespace, resType: resType, name: name, rank: "G", attrs: attrs})
+	g.hasNode[id] = true
+}
+
+// genHpaTargetRef generates the edges of HorizontalPodAutoscaler to its scale target
+func (g *Graph) genHpaTargetRef(res *resources.Resources) {
 	// Add edge if below matches:
-	//   - networking.k8s.io/v1beta1.Ingress.spec.rules.path[].backend.serviceName
-	//   - v1.Service.metadata.name
+	//   - autoscaling/v1.HorizontalPodAutoscaler.spec.scaleTargetRef.
+	//     - kind
+	//     - name
+	//   - {kind}.metadata.{name}
 	// ```
-	// svc_my_service->ing_my_ingress[ dir=back ];
+	// hpa_my_namespace__my_hpa->deploy_my_namespace__my_deployment[ color=blue ];
 	// ```
-	for _, ing := range g.res.Ingresses.Items {
-		for _, rule := range ing.Spec.Rules {
-			for _, path := range rule.IngressRuleValue.HTTP.Paths {
-				if !g.res.HasResource("svc", path.Backend.ServiceName) {
-					fmt.Fprintf(os.Stderr, "svc %s not found for ingress %s\n", path.Backend.ServiceName, ing.Name)
-					continue
-				}
+	for _, hpa := range res.Hpas.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		targetKind, err := resources.NormalizeResource(ref.Kind)
+		if err != nil {
+			// Skip resource that isn't available for this tool, like CRD
+			continue
+		}
+		if !res.HasResource(targetKind, ref.Name) {
+			g.resourceWarnf(res.Namespace, "hpa", hpa.Name, "%s %s not found as a scale target for hpa %s", targetKind, ref.Name, hpa.Name)
+			continue
+		}
 
-				g.gviz.AddEdge(g.resourceName("svc", path.Backend.ServiceName), g.resourceName("ing", ing.Name), true, map[string]string{"dir": "back"})
-			}
+		g.addEdge(edgeKindScaleTarget, g.resourceName(res.Namespace, "hpa", hpa.Name), g.resourceName(res.Namespace, targetKind, ref.Name),
+			g.theme.ScaleTarget.attrs(nil))
+	}
+}
+
+// genIngIngressClassRef generates the edges of Ingress to IngressClass reference
+func (g *Graph) genIngIngressClassRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - networking.k8s.io/v1.Ingress.spec.ingressClassName
+	//     (or kubernetes.io/ingress.class annotation, or the default class if neither is set)
+	//   - networking.k8s.io/v1.IngressClass.metadata.name
+	// ```
+	// ing_my_namespace__my_ingress->ingressclass_my_ingressclass;
+	// ```
+	for _, ing := range res.Ingresses.Items {
+		name := res.IngressClassName(&ing)
+		if name == "" {
+			continue
+		}
+		if !res.HasResource("ingressclass", name) {
+			g.resourceWarnf(res.Namespace, "ing", ing.Name, "ingressclass %s not found for ingress %s", name, ing.Name)
+			continue
 		}
+
+		g.addEdge(edgeKindIngressClass, g.resourceName(res.Namespace, "ing", ing.Name), g.resourceName(res.Namespace, "ingressclass", name), g.theme.Reference.attrs(nil))
 	}
 }
 
 // imagePath returns the path to the image file
-// path is {dir}/icons/{resource}-128.png
+// path is {dir}/icons/{resource}{suffix}, unless it is overridden by options.
+// suffix is "-128.png" by default.
 // ex) /icons/pod-128.png
 func (g *Graph) imagePath(resource string) string {
-	return filepath.Join(g.dir, "icons", resource+imageSuffix)
+	if path, ok := g.icons[resource]; ok {
+		return path
+	}
+	return filepath.Join(g.dir, "icons", resource+g.opts.iconSuffix())
 }
 
 // clusterLabel returns the resource label for namespace
 // ex)
 //   <<TABLE BORDER="0"><TR><IMG SRC="/icons/ns-128.png" /></TR><TR><TD>my-namespace</TD></TR></TABLE>>
-func (g *Graph) clusterLabel() string {
-	return g.resourceLabel("ns", g.res.Namespace)
+func (g *Graph) clusterLabel(namespace string) string {
+	if g.opts.ClusterLabel == "" {
+		return g.resourceLabel("ns", namespace)
+	}
+	return g.resourceLabel("ns", html.EscapeString(g.clusterCaption(namespace)))
+}
+
+// clusterCaption returns the text shown for namespace
+// It is ClusterLabel in options with {namespace} replaced, or namespace itself if not set.
+func (g *Graph) clusterCaption(namespace string) string {
+	if g.opts.ClusterLabel == "" {
+		return namespace
+	}
+	return strings.ReplaceAll(g.opts.ClusterLabel, "{namespace}", namespace)
 }
 
 // resourceLabel returns the resource label for a resource
 // ex)
 //   <<TABLE BORDER="0"><TR><IMG SRC="/icons/pod-128.png" /></TR><TR><TD>my-pod</TD></TR></TABLE>>
 func (g *Graph) resourceLabel(resType, name string) string {
-	return fmt.Sprintf("<<TABLE BORDER=\"0\"><TR><TD><IMG SRC=\"%s\" /></TD></TR><TR><TD>%s</TD></TR></TABLE>>", g.imagePath(resType), name)
+	return g.tableLabel(resType, []string{fmt.Sprintf("<TD>%s</TD>", name)})
+}
+
+// nodeLabel returns the label of the node for a resource
+// It is the resource label, where the name is colored depending on the state
+// of the resource, followed by rows for details enabled by options.
+// ex)
+//   <<TABLE BORDER="0"><TR><IMG SRC="/icons/pod-128.png" /></TR><TR><TD BGCOLOR="palegreen">my-pod</TD></TR></TABLE>>
+func (g *Graph) nodeLabel(res *resources.Resources, resType, name string) string {
+	shownName := name
+	if resType == "pod" {
+		shownName = g.collapsedPodLabel(res.Namespace, name)
+	}
+	nameCell := fmt.Sprintf("<TD>%s</TD>", shownName)
+	if color := g.nodeColor(res, resType, name); color != "" {
+		nameCell = fmt.Sprintf("<TD BGCOLOR=\"%s\">%s</TD>", color, shownName)
+	}
+
+	cells := []string{nameCell}
+	if g.opts.ShowReplicas {
+		if replicas := g.replicas(res, resType, name); replicas != "" {
+			cells = append(cells, fmt.Sprintf("<TD>%s</TD>", replicas))
+		}
+	}
+	if g.opts.ShowDisruptionBudget {
+		if budget := g.disruptionBudget(res, resType, name); budget != "" {
+			cells = append(cells, fmt.Sprintf("<TD>%s</TD>", budget))
+		}
+	}
+	if g.opts.ShowServiceType {
+		if svcType := g.serviceType(res, resType, name); svcType != "" {
+			cells = append(cells, fmt.Sprintf("<TD>%s</TD>", svcType))
+		}
+	}
+	if g.opts.ShowSchedule {
+		if schedule := g.schedule(res, resType, name); schedule != "" {
+			cells = append(cells, fmt.Sprintf("<TD>%s</TD>", schedule))
+		}
+	}
+	if g.opts.ShowImages {
+		for _, image := range g.images(res, resType, name) {
+			cells = append(cells, fmt.Sprintf("<TD>%s</TD>", image))
+		}
+	}
+	if g.opts.ShowContainerPorts {
+		if ports := g.containerPorts(res, resType, name); ports != "" {
+			cells = append(cells, fmt.Sprintf("<TD>%s</TD>", ports))
+		}
+	}
+	if g.opts.ShowAge {
+		if age := g.age(res, resType, name); age != "" {
+			cells = append(cells, fmt.Sprintf("<TD>%s</TD>", age))
+		}
+	}
+
+	return g.tableLabel(resType, cells)
+}
+
+// tableLabel returns the label with the icon of resType and rows for cells
+// The image row is omitted if no icon is found for the resource type.
+func (g *Graph) tableLabel(resType string, cells []string) string {
+	rows := ""
+	if _, err := os.Stat(g.imagePath(resType)); err == nil {
+		rows += fmt.Sprintf("<TR><TD><IMG SRC=\"%s\" /></TD></TR>", g.imagePath(resType))
+	}
+	for _, cell := range cells {
+		rows += "<TR>" + cell + "</TR>"
+	}
+
+	return fmt.Sprintf("<<TABLE BORDER=\"0\">%s</TABLE>>", rows)
+}
+
+// nodeColor returns the color of the node for a resource
+// Pods are colored by their phase, unless it is disabled by options, and
+// suspended cronjobs are grayed out.
+// It returns empty string if the node isn't colored.
+func (g *Graph) nodeColor(res *resources.Resources, resType, name string) string {
+	switch o := res.GetResource(resType, name).(type) {
+	case *corev1.Pod:
+		if g.opts.DisablePodPhaseColor {
+			return ""
+		}
+		return g.theme.PodPhaseColors[o.Status.Phase]
+	case *batchv1beta1.CronJob:
+		if o.Spec.Suspend != nil && *o.Spec.Suspend {
+			return g.theme.SuspendedCronJobColor
+		}
+	}
+
+	return ""
+}
+
+// nodeURL returns the URL of the node for a resource, expanded from the URL template
+// Each value is escaped to be used as a part of the URL path.
+// ex) https://console.example.com/ns/my-namespace/pod/my-pod
+func (g *Graph) nodeURL(namespace, resType, name string) string {
+	if resources.IsClusterScoped(resType) {
+		namespace = ""
+	}
+
+	return strings.NewReplacer(
+		"{namespace}", url.PathEscape(namespace),
+		"{kind}", url.PathEscape(resType),
+		"{name}", url.PathEscape(name),
+	).Replace(g.opts.URLTemplate)
 }
 
 // clusterName returns name of the graphviz cluster
 // It is named base on namespace.
 // ex) cluster_my_namespace
-func (g *Graph) clusterName() string {
-	return clusterPrefix + g.escapeName(g.res.Namespace)
+func (g *Graph) clusterName(namespace string) string {
+	return clusterPrefix + g.escapeName(namespace)
 }
 
 // escapeName returns the escaped name to be handled with graphviz
@@ -333,20 +1938,29 @@ func (g *Graph) escapeName(name string) string {
 }
 
 // resourceName returns the escaped name of the resource
-// It espaces the resource name and add resType as a prefix.
-// ex) pod_my_pod
-func (g *Graph) resourceName(resType, name string) string {
-	return resType + "_" + g.escapeName(name)
+// It espaces the namespace and the resource name, and add resType as a prefix.
+// The namespace is omitted for cluster-scoped resources, as they are shared
+// by all namespaces.
+// ex) pod_my_namespace__my_pod, ingressclass_my_ingressclass
+func (g *Graph) resourceName(namespace, resType, name string) string {
+	if resources.IsClusterScoped(resType) {
+		return resType + "_" + g.escapeName(name)
+	}
+	if resType == "pod" {
+		// Pods collapsed into a group share the node of the group
+		name = g.collapsedPodName(namespace, name)
+	}
+	return resType + "_" + g.escapeName(namespace) + "__" + g.escapeName(name)
 }
 
-// rankName returns the name of the dummy rank
-// ex) rank_1
-func (g *Graph) rankName(rank int) string {
-	return fmt.Sprintf("%s%d", rankPrefix, rank)
+// rankName returns the name of the dummy rank in the namespace
+// ex) rank_my_namespace_1
+func (g *Graph) rankName(namespace string, rank int) string {
+	return fmt.Sprintf("%s%s_%d", rankPrefix, g.escapeName(namespace), rank)
 }
 
-// rankDummyNodeName returns the node name of the dummy rank
-// ex) 1
-func (g *Graph) rankDummyNodeName(rank int) string {
-	return fmt.Sprintf("%d", rank)
+// rankDummyNodeName returns the node name of the dummy rank in the namespace
+// ex) dummy_my_namespace_1
+func (g *Graph) rankDummyNodeName(namespace string, rank int) string {
+	return fmt.Sprintf("%s%s_%d", rankDummyPrefix, g.escapeName(namespace), rank)
 }
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+)
+
+// iconsDir is the directory that has icons directory in the repository
+const iconsDir = "../.."
+
+// resourcesFromManifest returns Resources for the namespace read from the manifest
+func resourcesFromManifest(t *testing.T, namespace, manifest string) *resources.Resources {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := ioutil.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := resources.NewResourcesFromManifests(path, namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// countEdges returns the number of the edges from the node from to the node to in dot
+func countEdges(dot, from, to string) int {
+	return len(regexp.MustCompile(`(?m)^\s*`+regexp.QuoteMeta(from+"->"+to)+`\b`).FindAllString(dot, -1))
+}
+
+func TestOwnerReferencedTwice(t *testing.T) {
+	res := resourcesFromManifest(t, "default", `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: web-1
+  namespace: default
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: web
+    uid: "1"
+    controller: true
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: web
+    uid: "1"
+    controller: true
+`)
+
+	dot := GenerateDot(res, iconsDir)
+	if got := countEdges(dot, "deploy_default__web", "rs_default__web_1"); got != 1 {
+		t.Errorf("got %d edges from deploy to rs, want 1:\n%s", got, dot)
+	}
+}
+
+func TestGenerateDotIsReproducible(t *testing.T) {
+	manifest := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: web-1
+  namespace: default
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: web
+    uid: "1"
+    controller: true
+`
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		manifest += `---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-` + name + `
+  namespace: default
+  labels:
+    app: web
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: ReplicaSet
+    name: web-1
+    uid: "2"
+    controller: true
+spec:
+  volumes:
+  - name: config
+    configMap:
+      name: config-` + name + `
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config-` + name + `
+  namespace: default
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc-` + name + `
+  namespace: default
+spec:
+  selector:
+    app: web
+`
+	}
+
+	res := resourcesFromManifest(t, "default", manifest)
+	want := GenerateDot(res, iconsDir)
+
+	shuffled := resourcesFromManifest(t, "default", manifest)
+	r := rand.New(rand.NewSource(1))
+	r.Shuffle(len(shuffled.Pods.Items), func(i, j int) {
+		shuffled.Pods.Items[i], shuffled.Pods.Items[j] = shuffled.Pods.Items[j], shuffled.Pods.Items[i]
+	})
+	r.Shuffle(len(shuffled.Cms.Items), func(i, j int) {
+		shuffled.Cms.Items[i], shuffled.Cms.Items[j] = shuffled.Cms.Items[j], shuffled.Cms.Items[i]
+	})
+	r.Shuffle(len(shuffled.Svcs.Items), func(i, j int) {
+		shuffled.Svcs.Items[i], shuffled.Svcs.Items[j] = shuffled.Svcs.Items[j], shuffled.Svcs.Items[i]
+	})
+	if shuffled.Pods.Items[0].Name == res.Pods.Items[0].Name && shuffled.Svcs.Items[0].Name == res.Svcs.Items[0].Name {
+		t.Fatal("resources aren't shuffled")
+	}
+
+	if got := GenerateDot(shuffled, iconsDir); got != want {
+		t.Errorf("dot changed by the order of resources:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestIngressRuleWithoutHTTP(t *testing.T) {
+	res := resourcesFromManifest(t, "default", `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: default
+spec:
+  rules:
+  - host: web.example.com
+`)
+	if rules := res.Ingresses.Items[0].Spec.Rules; len(rules) != 1 || rules[0].HTTP != nil {
+		t.Fatalf("rules = %v, want a rule without http", rules)
+	}
+
+	dot := GenerateDot(res, iconsDir)
+	if got := countEdges(dot, "svc_default__web", "ing_default__web"); got != 0 {
+		t.Errorf("got %d edges from svc to ing, want 0:\n%s", got, dot)
+	}
+}
+
+func TestNamespaceOnlyWithDeployment(t *testing.T) {
+	res := resourcesFromManifest(t, "default", `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+`)
+
+	dot := GenerateDot(res, iconsDir)
+	// Deployments are in the second rank, and the other ranks are left empty
+	rank := regexp.MustCompile(`(?s)subgraph rank_default_1 \{[^}]*\bdeploy_default__web \[`)
+	if !rank.MatchString(dot) {
+		t.Errorf("deploy isn't in rank_default_1:\n%s", dot)
+	}
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+const (
+	// groupType is the type of the nodes for groups of resources that have the same annotation value
+	groupType = "group"
+)
+
+// generateGroups connects the resources in res to the nodes of their groups
+// Resources are grouped by the value of the annotation specified by
+// GroupAnnotation in options, like app.kubernetes.io/part-of, and each group
+// is shown as a node with rounded gray box in the top rank of the namespace.
+// ```
+// group_my_namespace__my_app [ color=gray, label="my-app", shape=box, style=rounded ];
+// group_my_namespace__my_app->deploy_my_namespace__my_deployment[ color=darkgreen, dir=none, style=dotted ];
+// ```
+func (g *Graph) generateGroups(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - {kind}.metadata.annotations[{annotation}]
+	for _, resType := range resourceTypes() {
+		for _, name := range res.GetResourceNames(resType) {
+			from := g.resourceName(res.Namespace, resType, name)
+			if !g.hasNode[from] {
+				// Skip resource that isn't shown, like excluded types
+				continue
+			}
+			m, err := meta.Accessor(res.GetResource(resType, name))
+			if err != nil {
+				continue
+			}
+			value, ok := m.GetAnnotations()[g.opts.GroupAnnotation]
+			if !ok || value == "" {
+				continue
+			}
+
+			id := g.groupName(res.Namespace, value)
+			if !g.hasNode[id] {
+				attrs := g.theme.Group.attrs(map[string]string{"label": fmt.Sprintf("%q", value), "shape": "box"})
+				g.nodes = append(g.nodes, node{id: id, namespace: res.Namespace, resType: groupType, name: value, rank: g.rankName(res.Namespace, 0), attrs: attrs})
+				g.hasNode[id] = true
+			}
+			g.addEdge(edgeKindGroup, id, from, g.theme.GroupMember.attrs(map[string]string{"dir": "none"}))
+		}
+	}
+}
+
+// groupName returns the id of the node for the group in the namespace
+// ex) group_my_namespace__my_app
+func (g *Graph) groupName(namespace, value string) string {
+	return groupType + "_" + g.escapeName(namespace) + "__" + invalidIDChars.ReplaceAllString(value, "_")
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mkimuram/k8sviz/icons"
+)
+
+var (
+	// embeddedIconsOnce makes embedded icons extracted only once in a process
+	embeddedIconsOnce sync.Once
+	// embeddedIconsDir is the directory that has icons directory for embedded icons
+	// It is empty if the icons failed to be extracted.
+	embeddedIconsDir string
+)
+
+// embeddedDir returns the directory that has icons directory for embedded icons
+// Embedded icons are extracted at the first call, as graphviz only reads
+// images from files. They are extracted to the cache directory of the user,
+// named by the hash of the icons, so that other users can't replace them and
+// the icons of other versions aren't mixed. A new temporary directory only
+// for the user is used instead, if the user has no cache directory.
+func embeddedDir() string {
+	embeddedIconsOnce.Do(func() {
+		dir, err := embeddedIconsCacheDir()
+		if err != nil {
+			dir, err = ioutil.TempDir("", embeddedIconsDirName+"-")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create directory to extract embedded icons: %v\n", err)
+			return
+		}
+		if err := extractIcons(filepath.Join(dir, "icons")); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to extract embedded icons to %q: %v\n", dir, err)
+			return
+		}
+		embeddedIconsDir = dir
+	})
+
+	return embeddedIconsDir
+}
+
+// embeddedIconsCacheDir returns the directory in the cache directory of the user to extract embedded icons
+// ex) ~/.cache/k8sviz-icons/0123456789abcdef
+func embeddedIconsCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	hash, err := embeddedIconsHash()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, embeddedIconsDirName, hash), nil
+}
+
+// embeddedIconsHash returns the hash of the names and the contents of embedded icons
+func embeddedIconsHash() (string, error) {
+	entries, err := fs.ReadDir(icons.FS, ".")
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, entry := range entries {
+		data, err := icons.FS.ReadFile(entry.Name())
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d\n", entry.Name(), len(data))
+		h.Write(data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// extractIcons writes the embedded icons to dir
+// Icons already in dir with the same contents are kept. Each icon is written to a temporary file and renamed, not to let other
+// processes read partially written icons.
+func extractIcons(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	entries, err := fs.ReadDir(icons.FS, ".")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		data, err := icons.FS.ReadFile(entry.Name())
+		if err != nil {
+			return err
+		}
+		if current, err := ioutil.ReadFile(filepath.Join(dir, entry.Name())); err == nil && bytes.Equal(current, data) {
+			// Already extracted by the previous run
+			continue
+		}
+
+		f, err := ioutil.TempFile(dir, entry.Name())
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), 0644)
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), filepath.Join(dir, entry.Name()))
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mkimuram/k8sviz/icons"
+)
+
+func TestExtractIcons(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	dir, err := embeddedIconsCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dir, filepath.Join(cacheDir, embeddedIconsDirName)+string(filepath.Separator)) {
+		t.Fatalf("dir = %q, want in %q", dir, cacheDir)
+	}
+
+	iconsDir := filepath.Join(dir, "icons")
+	path := filepath.Join(iconsDir, "pod-128.png")
+	want, err := icons.FS.ReadFile("pod-128.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract := func(desc string) {
+		t.Helper()
+		if err := extractIcons(iconsDir); err != nil {
+			t.Fatalf("%s: failed to extract icons: %v", desc, err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", desc, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: icon differs from the embedded one", desc)
+		}
+	}
+
+	extract("new directory")
+	extract("extracted directory")
+	// Icons replaced by others are restored by the next extraction
+	if err := ioutil.WriteFile(path, []byte("replaced"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	extract("replaced icon")
+}
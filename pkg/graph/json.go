@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"encoding/json"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+)
+
+// jsonGraph represents the graph in JSON output
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+// jsonNode represents a node in JSON output
+// Namespace is omitted for cluster-scoped resources.
+type jsonNode struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// jsonEdge represents an edge in JSON output
+// From and To are the ids of the nodes.
+type jsonEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// GenerateJSON returns the graph of k8s resources with JSON format
+// The namespace of the graph is the one in res.
+func GenerateJSON(res *resources.Resources) ([]byte, error) {
+	return NewGraph(res, "").toJSON()
+}
+
+// WriteJSONFile writes the graph to outFile with JSON format
+// The graph is written to standard output, if outFile is "-".
+func (g *Graph) WriteJSONFile(outFile string) error {
+	b, err := g.toJSON()
+	if err != nil {
+		return err
+	}
+
+	return g.writeFile(outFile, writeString(string(b)+"\n"))
+}
+
+// toJSON returns the JSON representation of the graph
+// It contains the same nodes and edges for k8s resources as toDot, like below.
+// ```
+//   {
+//     "nodes": [
+//       {"id": "deploy_my_namespace__my_deployment", "type": "deploy", "name": "my-deployment", "namespace": "my-namespace"},
+//       {"id": "rs_my_namespace__my_replicaset", "type": "rs", "name": "my-replicaset", "namespace": "my-namespace"}
+//     ],
+//     "edges": [
+//       {"from": "deploy_my_namespace__my_deployment", "to": "rs_my_namespace__my_replicaset", "kind": "owner-reference"}
+//     ]
+//   }
+// ```
+// Edges with dir=back are reversed, for the edges to point the same nodes as the arrows.
+func (g *Graph) toJSON() ([]byte, error) {
+	jg := jsonGraph{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+	for _, n := range g.nodes {
+		jn := jsonNode{ID: n.id, Type: n.resType, Name: n.name, Namespace: n.namespace}
+		if resources.IsClusterScoped(n.resType) {
+			jn.Namespace = ""
+		}
+		jg.Nodes = append(jg.Nodes, jn)
+	}
+	for _, e := range g.edges {
+		from, to := e.from, e.to
+		if e.attrs["dir"] == "back" {
+			from, to = to, from
+		}
+		jg.Edges = append(jg.Edges, jsonEdge{From: from, To: to, Kind: e.kind})
+	}
+
+	return json.MarshalIndent(jg, "", "  ")
+}
@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// replicas returns ready and desired replicas of a workload resource
+// ex) 2/3
+// It returns empty string for resource types that don't have replicas.
+func (g *Graph) replicas(res *resources.Resources, resType, name string) string {
+	switch o := res.GetResource(resType, name).(type) {
+	case *appsv1.Deployment:
+		return fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, desiredReplicas(o.Spec.Replicas))
+	case *appsv1.ReplicaSet:
+		return fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, desiredReplicas(o.Spec.Replicas))
+	case *appsv1.StatefulSet:
+		return fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, desiredReplicas(o.Spec.Replicas))
+	case *corev1.ReplicationController:
+		return fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, desiredReplicas(o.Spec.Replicas))
+	case *appsv1.DaemonSet:
+		return fmt.Sprintf("%d/%d", o.Status.NumberReady, o.Status.DesiredNumberScheduled)
+	case *unstructured.Unstructured:
+		if resType == "dc" {
+			desired, _, _ := unstructured.NestedInt64(o.Object, "spec", "replicas")
+			ready, _, _ := unstructured.NestedInt64(o.Object, "status", "readyReplicas")
+			return fmt.Sprintf("%d/%d", ready, desired)
+		}
+	}
+
+	return ""
+}
+
+// disruptionBudget returns minAvailable or maxUnavailable of a pdb
+// ex) minAvailable: 2, maxUnavailable: 25%
+// It returns empty string for resource types other than pdb.
+func (g *Graph) disruptionBudget(res *resources.Resources, resType, name string) string {
+	pdb, ok := res.GetResource(resType, name).(*policyv1beta1.PodDisruptionBudget)
+	if !ok {
+		return ""
+	}
+
+	switch {
+	case pdb.Spec.MinAvailable != nil:
+		return fmt.Sprintf("minAvailable: %s", pdb.Spec.MinAvailable.String())
+	case pdb.Spec.MaxUnavailable != nil:
+		return fmt.Sprintf("maxUnavailable: %s", pdb.Spec.MaxUnavailable.String())
+	}
+
+	return ""
+}
+
+// serviceType returns the type of a service with where it is exposed, except for ClusterIP
+// ex) LoadBalancer: 203.0.113.1, LoadBalancer: <pending>, NodePort: 30080, ExternalName: db.example.com
+// It returns empty string for ClusterIP services and resource types other than svc.
+func (g *Graph) serviceType(res *resources.Resources, resType, name string) string {
+	svc, ok := res.GetResource(resType, name).(*corev1.Service)
+	if !ok {
+		return ""
+	}
+
+	exposed := []string{}
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ing := range svc.Status.LoadBalancer.Ingress {
+			if ing.IP != "" {
+				exposed = append(exposed, ing.IP)
+			} else if ing.Hostname != "" {
+				exposed = append(exposed, ing.Hostname)
+			}
+		}
+		if len(exposed) == 0 {
+			// External IP isn't assigned yet
+			exposed = append(exposed, "&lt;pending&gt;")
+		}
+	case corev1.ServiceTypeNodePort:
+		for _, port := range svc.Spec.Ports {
+			if port.NodePort != 0 {
+				exposed = append(exposed, fmt.Sprintf("%d", port.NodePort))
+			}
+		}
+	case corev1.ServiceTypeExternalName:
+		exposed = append(exposed, svc.Spec.ExternalName)
+	default:
+		return ""
+	}
+
+	if len(exposed) == 0 {
+		return string(svc.Spec.Type)
+	}
+	return fmt.Sprintf("%s: %s", svc.Spec.Type, strings.Join(exposed, ", "))
+}
+
+// schedule returns the schedule of a cronjob, with its description if it is a common one
+// ex) */5 * * * * (every 5 minutes), 0 0 1 1 *
+// It returns empty string for resource types other than cronjob.
+func (g *Graph) schedule(res *resources.Resources, resType, name string) string {
+	cronJob, ok := res.GetResource(resType, name).(*batchv1beta1.CronJob)
+	if !ok {
+		return ""
+	}
+
+	if desc, ok := describeSchedule(cronJob.Spec.Schedule); ok {
+		return fmt.Sprintf("%s (%s)", cronJob.Spec.Schedule, desc)
+	}
+	return cronJob.Spec.Schedule
+}
+
+// images returns the images of the init containers and the containers in a pod, without their registries
+// ex) [nginx:1.19 my-team/my-app:v1]
+// It returns empty list for resource types other than pod.
+func (g *Graph) images(res *resources.Resources, resType, name string) []string {
+	pod, ok := res.GetResource(resType, name).(*corev1.Pod)
+	if !ok {
+		return []string{}
+	}
+
+	images := []string{}
+	for _, c := range pod.Spec.InitContainers {
+		images = append(images, trimRegistry(c.Image))
+	}
+	for _, c := range pod.Spec.Containers {
+		images = append(images, trimRegistry(c.Image))
+	}
+	return images
+}
+
+// containerPorts returns the ports of the containers in a pod
+// ex) ports: 8080, metrics:9090, dns:53/UDP
+// Named ports are shown with their names, and protocols other than TCP are
+// shown after the ports. It returns empty string for pods without ports and
+// resource types other than pod.
+func (g *Graph) containerPorts(res *resources.Resources, resType, name string) string {
+	pod, ok := res.GetResource(resType, name).(*corev1.Pod)
+	if !ok {
+		return ""
+	}
+
+	ports := []string{}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			port := fmt.Sprintf("%d", p.ContainerPort)
+			if p.Name != "" {
+				port = p.Name + ":" + port
+			}
+			if p.Protocol != "" && p.Protocol != corev1.ProtocolTCP {
+				port += "/" + string(p.Protocol)
+			}
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return ""
+	}
+	return "ports: " + strings.Join(ports, ", ")
+}
+
+// trimRegistry returns the image without the registry
+// ex) docker.io/library/nginx:1.19 -> library/nginx:1.19
+// The first component of the image is handled as the registry, if it has "." or ":"
+// or is "localhost", in the same way as docker.
+func trimRegistry(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[1]
+	}
+	return image
+}
+
+// age returns the time since the creation of a resource, in the same format as AGE of kubectl
+// ex) 3d, 5h
+// It returns empty string if the creation timestamp isn't set, like resources in manifests.
+func (g *Graph) age(res *resources.Resources, resType, name string) string {
+	m, err := meta.Accessor(res.GetResource(resType, name))
+	if err != nil {
+		return ""
+	}
+	created := m.GetCreationTimestamp()
+	if created.IsZero() {
+		return ""
+	}
+
+	return duration.HumanDuration(time.Since(created.Time))
+}
+
+// tooltip returns the tooltip of the node for a resource
+// It has the labels of the resource, and the annotations if enabled by options,
+// in separate lines like below.
+// ```
+// pod/my-pod
+// labels:
+//   app=my-app
+// ```
+func (g *Graph) tooltip(obj runtime.Object, resType, name string) string {
+	lines := []string{resType + "/" + name}
+	m, err := meta.Accessor(obj)
+	if obj == nil || err != nil {
+		return lines[0]
+	}
+
+	if len(m.GetLabels()) > 0 {
+		lines = append(lines, "labels:")
+		lines = append(lines, keyValues(m.GetLabels())...)
+	}
+	if g.opts.ShowTooltipAnnotations && len(m.GetAnnotations()) > 0 {
+		lines = append(lines, "annotations:")
+		lines = append(lines, keyValues(m.GetAnnotations())...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// keyValues returns the indented key=value lines of m sorted by keys
+func keyValues(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := []string{}
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("  %s=%s", k, m[k]))
+	}
+	return lines
+}
+
+// desiredReplicas returns the number of replicas in spec
+// It is 1 if not specified, as k8s defaults it to 1.
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
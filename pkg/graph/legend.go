@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// legendName is the name of the cluster for the legend
+	legendName = clusterPrefix + "legend"
+	// legendPrefix is the prefix of the nodes in the legend
+	legendPrefix = "legend_"
+)
+
+// legendEdge represents a kind of edges explained in the legend
+type legendEdge struct {
+	// description is shown as the label of the sample edge
+	description string
+	// attrs is the attributes of the edges of the kind
+	attrs map[string]string
+}
+
+// legendEdges returns the list of the kinds of edges explained in the legend
+// attrs should be the same as the ones in generateEdges.
+func legendEdges(t *Theme) []legendEdge {
+	return []legendEdge{
+		{"owner reference", t.OwnerReference.attrs(nil)},
+		{"owner reference of non-controller", t.NonControllerOwnerReference.attrs(nil)},
+		{"volume used by pod", t.Volume.attrs(map[string]string{"dir": "none"})},
+		{"configmap or secret used by pod", t.Config.attrs(map[string]string{"dir": "none"})},
+		{"service account used by pod", t.ServiceAccount.attrs(map[string]string{"dir": "none"})},
+		{"pod selected by service", t.ServiceSelector.attrs(map[string]string{"dir": "back"})},
+		{"service backing ingress", t.IngressBackend.attrs(map[string]string{"dir": "back"})},
+		{"pod selected by networkpolicy", t.NetworkPolicy.attrs(nil)},
+		{"peer of networkpolicy", t.NetworkPolicyPeer.attrs(nil)},
+		{"pod selected by poddisruptionbudget", t.DisruptionBudget.attrs(nil)},
+		{"scale target of hpa", t.ScaleTarget.attrs(nil)},
+		{"node that pod runs on", t.NodePlacement.attrs(nil)},
+		{"resource in group of annotation", t.GroupMember.attrs(map[string]string{"dir": "none"})},
+		{"other reference, like pvc to pv", t.Reference.attrs(nil)},
+	}
+}
+
+// generateLegend generates the cluster that explains the edges and the icons of the graph
+// Each kind of edges is shown as a sample edge from a point to its
+// description, and each resource type shown in the graph is shown with its
+// icon. The legend is a separate cluster without edges to the other nodes,
+// so it doesn't change the ranks of resources.
+// ```
+//   subgraph cluster_legend {
+//     label="Legend";
+//     labeljust=l;
+//     style=dotted;
+//     legend_edge_0 [ height=0.1, label="", shape=point, width=0.1 ];
+//     legend_edge_0_description [ label="owner reference", shape=plaintext ];
+//     legend_icon_pod [ label=<<TABLE BORDER="0"><TR><TD><IMG SRC="/icons/pod-128.png" /></TD></TR><TR><TD>pod</TD></TR></TABLE>>, penwidth=0 ];
+//   }
+//   legend_edge_0->legend_edge_0_description[ style=dashed ];
+// ```
+func (g *Graph) generateLegend() {
+	g.gviz.AddSubGraph("G", legendName,
+		g.theme.Namespace.attrs(map[string]string{"label": fmt.Sprintf("%q", "Legend"), "labeljust": "l"}))
+
+	for i, e := range legendEdges(g.theme) {
+		from := fmt.Sprintf("%sedge_%d", legendPrefix, i)
+		to := from + "_description"
+		g.gviz.AddNode(legendName, from, map[string]string{"shape": "point", "label": "\"\"", "width": "0.1", "height": "0.1"})
+		g.gviz.AddNode(legendName, to, g.withFont(map[string]string{"shape": "plaintext", "label": fmt.Sprintf("%q", e.description)}, true))
+		g.gviz.AddEdge(from, to, true, e.attrs)
+	}
+
+	for _, resType := range append([]string{"ns"}, resourceTypes()...) {
+		if g.opts.isExcluded(resType) {
+			continue
+		}
+		path := g.imagePath(resType)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		label := fmt.Sprintf("<<TABLE BORDER=\"0\"><TR><TD><IMG SRC=\"%s\" /></TD></TR><TR><TD>%s</TD></TR></TABLE>>", path, resType)
+		g.gviz.AddNode(legendName, legendPrefix+"icon_"+resType, g.withFont(map[string]string{"label": label, "penwidth": "0"}, true))
+	}
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+)
+
+// GenerateMermaid returns the graph of k8s resources with mermaid flowchart format
+// The namespace of the graph is the one in res.
+func GenerateMermaid(res *resources.Resources) string {
+	return NewGraph(res, "").toMermaid()
+}
+
+// WriteMermaidFile writes the graph to outFile with mermaid flowchart format
+// The graph is written to standard output, if outFile is "-".
+func (g *Graph) WriteMermaidFile(outFile string) error {
+	return g.writeFile(outFile, writeString(g.toMermaid()))
+}
+
+// toMermaid returns a string representation of the graph with mermaid flowchart format
+// It contains the same nodes and edges for k8s resources as toDot, like below.
+// ```
+// graph TD
+//   subgraph cluster_my_namespace ["my-namespace"]
+//     deploy_my_namespace__my_deployment["my-deployment"]
+//     rs_my_namespace__my_replicaset["my-replicaset"]
+//   end
+//   deploy_my_namespace__my_deployment -.-> rs_my_namespace__my_replicaset
+// ```
+// Each namespace is shown as a subgraph, unless it is omitted by options.
+// Ranks aren't generated, as mermaid has no way to align nodes to a rank.
+func (g *Graph) toMermaid() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "graph %s\n", g.opts.rankDir())
+	for _, res := range g.resList {
+		if g.opts.OmitCluster {
+			for _, n := range g.nodes {
+				if n.namespace == res.Namespace {
+					fmt.Fprintf(&b, "  %s[\"%s\"]\n", n.id, n.name)
+				}
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "  subgraph %s [\"%s\"]\n", g.clusterName(res.Namespace), g.clusterCaption(res.Namespace))
+		for _, n := range g.nodes {
+			if n.namespace == res.Namespace {
+				fmt.Fprintf(&b, "    %s[\"%s\"]\n", n.id, n.name)
+			}
+		}
+		fmt.Fprintf(&b, "  end\n")
+	}
+	// Placeholders of the resources in the namespaces that aren't in the graph
+	for _, n := range g.nodes {
+		if _, ok := g.resourcesOf(n.namespace); !ok {
+			fmt.Fprintf(&b, "  %s[\"%s/%s\"]\n", n.id, n.namespace, n.name)
+		}
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "  %s\n", mermaidEdge(e))
+	}
+
+	return b.String()
+}
+
+// mermaidEdge returns the mermaid representation of the edge
+// Attributes of the edge are mapped as below:
+//   - style=dashed: -.-> (-.- for dir=none)
+//   - otherwise:    --> (--- for dir=none)
+//   - dir=back:     source and target are swapped for the arrow to point the same node
+//   - label:        text on the link, like -->|"80->8080"|
+func mermaidEdge(e edge) string {
+	from, to := e.from, e.to
+	if e.attrs["dir"] == "back" {
+		from, to = to, from
+	}
+
+	dashed, undirected := e.attrs["style"] == "dashed", e.attrs["dir"] == "none"
+	link := "-->"
+	switch {
+	case dashed && undirected:
+		link = "-.-"
+	case dashed:
+		link = "-.->"
+	case undirected:
+		link = "---"
+	}
+
+	if label, err := strconv.Unquote(e.attrs["label"]); err == nil {
+		link += "|\"" + label + "\"|"
+	}
+
+	return fmt.Sprintf("%s %s %s", from, link, to)
+}
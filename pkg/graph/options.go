@@ -0,0 +1,449 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/awalterschulze/gographviz"
+	"github.com/mkimuram/k8sviz/pkg/resources"
+)
+
+const (
+	// ViewStorage shows pods with pvcs, pvs, storageclasses and volume snapshots
+	ViewStorage = "storage"
+	// ViewNetworking shows pods with services, ingresses, routes, ingressclasses and networkpolicies
+	ViewNetworking = "networking"
+	// ViewWorkloads shows pods with their controllers, hpas, poddisruptionbudgets and nodes
+	ViewWorkloads = "workloads"
+	// ViewRBAC shows pods with serviceaccounts, rolebindings, roles and clusterroles
+	ViewRBAC = "rbac"
+)
+
+var (
+	// viewTypes is the map of views to the resource types shown in them
+	viewTypes = map[string][]string{
+		ViewStorage:    {"pod", "pvc", "pv", "storageclass", "vs", "vsc"},
+		ViewNetworking: {"pod", "svc", "ing", "route", "ingressclass", "netpol"},
+		ViewWorkloads:  {"pod", "deploy", "dc", "rs", "rc", "sts", "ds", "job", "cronjob", "hpa", "pdb", "node"},
+		ViewRBAC:       {"pod", "sa", "rolebinding", "role", "clusterrole"},
+	}
+)
+
+// Options represents the options to generate a graph
+// Zero value of each field keeps the default behavior.
+type Options struct {
+	// RankDir is the direction of the layout, one of TB, BT, LR and RL.
+	// TD, which has been used by k8sviz and is handled as TB by graphviz,
+	// is set if empty.
+	RankDir string
+	// DisablePodPhaseColor disables coloring pod names by their phase.
+	// Suspended cronjobs are grayed out regardless of it.
+	DisablePodPhaseColor bool
+	// DisableTypeColor disables the boxes of nodes colored by their resource
+	// types. The boxes that mark resources, like the dashed ones of
+	// cluster-scoped resources, are drawn regardless of it.
+	DisableTypeColor bool
+	// HideSucceededPods hides pods in Succeeded phase, like the ones of
+	// completed jobs, with the edges from and to them.
+	HideSucceededPods bool
+	// HideFailedPods hides pods in Failed phase with the edges from and to them.
+	HideFailedPods bool
+	// ShowReplicas shows ready/desired replicas of deployments, replicasets,
+	// statefulsets and daemonsets in their labels.
+	ShowReplicas bool
+	// ShowDisruptionBudget shows minAvailable or maxUnavailable of
+	// poddisruptionbudgets in their labels.
+	ShowDisruptionBudget bool
+	// ShowServiceType shows the types of services other than ClusterIP in
+	// their labels, with external IPs or hostnames of LoadBalancer services,
+	// node ports of NodePort services and external names of ExternalName services.
+	ShowServiceType bool
+	// ShowSchedule shows the schedules of cronjobs in their labels, with the
+	// descriptions of the common ones, like "*/5 * * * * (every 5 minutes)".
+	ShowSchedule bool
+	// ShowImages shows the images of the containers of pods in their labels,
+	// one image per row without the registry, like "nginx:1.19".
+	ShowImages bool
+	// ShowContainerPorts shows the ports of the containers of pods in their
+	// labels, like "ports: 8080, metrics:9090", to correlate them with target
+	// ports of services.
+	ShowContainerPorts bool
+	// ShowAge shows the time since the creation of resources in their labels,
+	// in the same format as AGE of kubectl, like "3d".
+	ShowAge bool
+	// ShowServicePorts shows the mappings of service ports to container ports
+	// as labels of the edges between services and pods, like "80->8080".
+	ShowServicePorts bool
+	// ShowTooltips sets the labels of resources as tooltips of their nodes,
+	// which are shown on hover in svg output.
+	ShowTooltips bool
+	// ShowTooltipAnnotations adds the annotations of resources to the tooltips.
+	// It is effective only with ShowTooltips.
+	ShowTooltipAnnotations bool
+	// ShowNonControllerOwners shows the edges from the owners without
+	// controller: true, in addition to the controllers of resources.
+	ShowNonControllerOwners bool
+	// CollapsePods shows pods owned by the same controller as a single node,
+	// labeled with the name of the controller and the number of the pods,
+	// like "my-replicaset (3)". Edges of the pods are drawn from and to the node.
+	CollapsePods bool
+	// CollapseReplicaSets hides replicasets owned by deployments, and draws
+	// the owner references from the deployments to the pods of the replicasets
+	// directly. The other replicasets, like orphaned ones, are shown as usual.
+	CollapseReplicaSets bool
+	// ShowPodTemplates shows the pod templates of workloads without pods, like
+	// deployments scaled to zero, as ghost pods named "{workload}-template"
+	// with dashed gray box. They are connected to the resources that the
+	// pods would use, like configmaps and services selecting them.
+	ShowPodTemplates bool
+	// OmitCluster omits the box for each namespace, and resources are placed
+	// directly in the graph. ClusterLabel has no effect with it.
+	OmitCluster bool
+	// ClusterLabel is the text shown for each namespace instead of its name.
+	// {namespace} in it is replaced with the name of the namespace.
+	// ex) Production - {namespace}
+	ClusterLabel string
+	// Title is the text shown at the top of the graph, which isn't shown if empty.
+	// {namespaces}, {context} and {time} in it are replaced with the comma
+	// separated namespaces, Context, and the time to generate the graph.
+	// ex) {context}: {namespaces} at {time}
+	Title string
+	// Context is the name of the kube context or the cluster, used for Title.
+	Context string
+	// Focus is the resource to focus on, like "deploy/my-deployment".
+	// Only the resources reachable from it within FocusDepth edges are shown,
+	// regardless of the directions of the edges.
+	// All the resources are shown if empty.
+	Focus string
+	// FocusDepth is the number of edges to follow from the resource to focus on.
+	// Only the resource itself is shown if zero, and its neighbors are also
+	// shown if one.
+	FocusDepth int
+	// ShowExternalVolumes shows the hostPath and CSI volumes of pods, which
+	// aren't k8s resources, as nodes with rounded dashed gray box connected to
+	// the pods. Volumes with the same path or driver are shown as a node.
+	ShowExternalVolumes bool
+	// ShowExternalHosts shows the hosts outside the cluster that ExternalName
+	// services point to, like db.example.com, as nodes with rounded dashed
+	// gray box connected to the services. ExternalName services pointing to
+	// services in the cluster are connected to the services regardless.
+	ShowExternalHosts bool
+	// ShowNodes draws the edges from pods to the nodes that they run on,
+	// from spec.nodeName. Pods that aren't scheduled yet have no edge.
+	// Nodes need to be got with resources.FetchOptions.Nodes, or read from
+	// manifests.
+	ShowNodes bool
+	// ExcludeTypes is the list of resource types not to be shown, like "svc".
+	// Edges from and to the resources of the types are also not shown.
+	ExcludeTypes []string
+	// IncludeTypes is the list of the only resource types to be shown, like
+	// "deploy" and "svc", and the other types are excluded. It can't be set
+	// with ExcludeTypes or View. All resource types are shown if empty.
+	IncludeTypes []string
+	// View is the predefined set of resource types to show, one of ViewStorage,
+	// ViewNetworking, ViewWorkloads and ViewRBAC, as a shorthand of excluding
+	// the other types. ExcludeTypes are excluded also from the view.
+	// All resource types are shown if empty.
+	View string
+	// UseEndpoints draws edges between services and pods from Endpoints,
+	// instead of matching service selectors with pod labels.
+	UseEndpoints bool
+	// ShowEndpointReadiness draws the edges between services and the pods
+	// that aren't ready with dashed red line, based on EndpointSlices.
+	// It is only for UseEndpoints.
+	ShowEndpointReadiness bool
+	// URLTemplate is the template of the URL set to each node, which makes
+	// the node clickable in svg output. {namespace}, {kind} and {name} in it
+	// are replaced with the ones of the resource, where {kind} is the resource
+	// type like "pod" and {namespace} is empty for cluster-scoped resources.
+	// ex) https://console.example.com/ns/{namespace}/{kind}/{name}
+	URLTemplate string
+	// ShowNetworkPolicyPeers shows the edges between networkpolicies and pods
+	// selected by podSelector of their ingress and egress rules, in addition
+	// to the pods that the networkpolicies apply to.
+	ShowNetworkPolicyPeers bool
+	// Splines is the splines attribute of the graph, which controls how edges
+	// are drawn, like "ortho" for orthogonal edges. It is one of none, line,
+	// false, polyline, curved, ortho, spline and true.
+	// It isn't set if empty, and graphviz draws edges as splines.
+	Splines string
+	// Overlap is the overlap attribute of the graph, which controls how
+	// overlapping nodes are removed by layout engines other than dot, like
+	// "false" or "scale". It isn't set if empty.
+	Overlap string
+	// Ranks is the ordered list of ranks, each of which is the space separated
+	// resource types placed in the same rank, like "deploy cronjob".
+	// Every resource type that isn't excluded must be in one of the ranks.
+	// resources.ResourceTypes is used if empty.
+	Ranks []string
+	// Layout is the graphviz layout engine to plot the graph, one of dot,
+	// neato, fdp, sfdp, circo, twopi, patchwork and osage.
+	// dot is used if empty.
+	Layout string
+	// PlotTimeout is the time limit to plot the graph with the layout engine.
+	// The process of the layout engine is killed after the timeout.
+	// No timeout is set if zero.
+	PlotTimeout time.Duration
+	// Progress is written the messages on plotting the graph, like
+	// "rendering png file k8sviz.png...". Nothing is written if nil.
+	Progress io.Writer
+	// WarningOut is written the warnings on generating and plotting the graph,
+	// like references to the resources that aren't found. They are written to
+	// stderr if nil, and can be discarded with ioutil.Discard. Warnings are
+	// kept regardless of WarningOut, which are returned by Graph.Warnings.
+	WarningOut io.Writer
+	// MaxNodes is the maximum number of nodes in the graph, to avoid plotting
+	// the graph too large to use, which may exhaust memory of the layout engine.
+	// Generating the graph fails with the numbers of nodes for each resource
+	// type, if the graph has more nodes. No limit is set if zero.
+	MaxNodes int
+	// ContinuePlotOnError continues plotting the other outputs after the
+	// failure to plot one of them with PlotDotFiles.
+	ContinuePlotOnError bool
+	// CreateOutputDir creates the directories of output files, if they don't
+	// exist. Writing and plotting files fail for missing directories if false.
+	CreateOutputDir bool
+	// Icons is the map of resource type to the icon file, like "pod": "/path/to/pod.png",
+	// which overrides the icon of the resource type. "ns" is for the namespace.
+	// The default icon is used, if the file doesn't exist.
+	Icons map[string]string
+	// GroupAnnotation is the annotation key to group resources by its value,
+	// like "app.kubernetes.io/part-of". Each group is shown as a node
+	// connected to the resources in the group with dotted dark green line.
+	// Resources aren't grouped if empty.
+	GroupAnnotation string
+	// FontName is the font of the labels in the graph, like "Helvetica".
+	// The default font of graphviz is used if empty.
+	FontName string
+	// FontSize is the font size of the labels in points, like 16.
+	// The default size of graphviz is used if zero.
+	FontSize float64
+	// NodeMargin is the margin around the labels of nodes in inches, like 0.2.
+	// The default margin of graphviz is used if zero.
+	NodeMargin float64
+	// TransparentBackground makes the background of the graph transparent,
+	// instead of white, for png and svg to be embedded in colored backgrounds.
+	TransparentBackground bool
+	// GraphAttrs is the map of graphviz attributes of the graph, like
+	// "bgcolor": "transparent" or "dpi": "150". They override the ones set by
+	// k8sviz, like rankdir, and values are quoted if needed.
+	GraphAttrs map[string]string
+	// Theme is the appearance of the nodes and the edges, like their colors.
+	// DefaultTheme() is used if nil.
+	Theme *Theme
+	// ShowLegend adds the legend that explains the styles of edges and the
+	// icons of resource types to the graph. It is only for dot format and
+	// the formats plotted by graphviz.
+	ShowLegend bool
+	// IconSuffix is appended to resource types to get the icon files in the
+	// icons directory, like "-128.svg" for icons/pod-128.svg.
+	// "-128.png" is used if empty. The embedded icons are only in png, so a
+	// directory that has the icons in the other format is needed for it.
+	IconSuffix string
+}
+
+// Validate checks if the options have valid values
+func (o *Options) Validate() error {
+	if o.RankDir != "" && o.RankDir != defaultRankDir && !contains(rankDirs, o.RankDir) {
+		return fmt.Errorf("invalid rankdir %q, must be one of %v", o.RankDir, rankDirs)
+	}
+
+	if o.Layout != "" && !contains(layouts, o.Layout) {
+		return fmt.Errorf("invalid layout %q, must be one of %v", o.Layout, layouts)
+	}
+
+	if o.ShowEndpointReadiness && !o.UseEndpoints {
+		return fmt.Errorf("readiness of endpoints can be shown only with endpoints")
+	}
+
+	if o.MaxNodes < 0 {
+		return fmt.Errorf("invalid maximum number of nodes %d, must not be negative", o.MaxNodes)
+	}
+
+	if o.FontSize < 0 {
+		return fmt.Errorf("invalid font size %v, must not be negative (0 for the default of graphviz)", o.FontSize)
+	}
+
+	if o.NodeMargin < 0 {
+		return fmt.Errorf("invalid node margin %v, must not be negative", o.NodeMargin)
+	}
+
+	if o.Splines != "" && !contains(splinesValues, o.Splines) {
+		return fmt.Errorf("invalid splines %q, must be one of %v", o.Splines, splinesValues)
+	}
+
+	if o.Overlap != "" && !contains(overlapValues, o.Overlap) {
+		return fmt.Errorf("invalid overlap %q, must be one of %v", o.Overlap, overlapValues)
+	}
+
+	for t := range o.Icons {
+		if t != "ns" && !isResourceType(t) {
+			return fmt.Errorf("invalid resource type %q to override icon, must be ns or one of %v", t, resourceTypes())
+		}
+	}
+
+	for k := range o.GraphAttrs {
+		if _, err := gographviz.NewAttr(k); err != nil {
+			return fmt.Errorf("invalid graph attribute %q: %v", k, err)
+		}
+	}
+
+	if o.IconSuffix != "" && !contains(iconExts, strings.ToLower(filepath.Ext(o.IconSuffix))) {
+		return fmt.Errorf("invalid icon suffix %q, must end with one of %v", o.IconSuffix, iconExts)
+	}
+
+	if _, ok := viewTypes[o.View]; o.View != "" && !ok {
+		return fmt.Errorf("invalid view %q, must be one of %v", o.View, views())
+	}
+
+	for _, t := range o.ExcludeTypes {
+		if !isResourceType(t) {
+			return fmt.Errorf("invalid resource type %q to exclude, must be one of %v", t, resourceTypes())
+		}
+	}
+	for _, t := range o.IncludeTypes {
+		if !isResourceType(t) {
+			return fmt.Errorf("invalid resource type %q to include, must be one of %v", t, resourceTypes())
+		}
+	}
+	if len(o.IncludeTypes) > 0 && (len(o.ExcludeTypes) > 0 || o.View != "") {
+		return fmt.Errorf("resource types to include can't be specified with the ones to exclude or view")
+	}
+
+	if o.Focus != "" {
+		parts := strings.SplitN(o.Focus, "/", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("invalid resource %q to focus on, must be type/name", o.Focus)
+		}
+		if !isResourceType(parts[0]) {
+			return fmt.Errorf("invalid resource type %q to focus on, must be one of %v", parts[0], resourceTypes())
+		}
+	}
+
+	if o.FocusDepth < 0 {
+		return fmt.Errorf("invalid focus depth %d, must not be negative", o.FocusDepth)
+	}
+
+	ranked := map[string]bool{}
+	for _, rankRes := range o.Ranks {
+		for _, t := range strings.Fields(rankRes) {
+			if !isResourceType(t) {
+				return fmt.Errorf("invalid resource type %q in ranks, must be one of %v", t, resourceTypes())
+			}
+			if ranked[t] {
+				return fmt.Errorf("resource type %q is in multiple ranks", t)
+			}
+			ranked[t] = true
+		}
+	}
+	if len(o.Ranks) > 0 {
+		for _, t := range resourceTypes() {
+			if !ranked[t] && !o.isExcluded(t) {
+				return fmt.Errorf("resource type %q isn't in ranks, add it to ranks or exclude it", t)
+			}
+		}
+	}
+
+	return nil
+}
+
+// rankDir returns the rankdir attribute of the graph
+func (o *Options) rankDir() string {
+	if o.RankDir == "" {
+		return defaultRankDir
+	}
+	return o.RankDir
+}
+
+// layout returns the graphviz layout engine to plot the graph
+func (o *Options) layout() string {
+	if o.Layout == "" {
+		return defaultLayout
+	}
+	return o.Layout
+}
+
+// iconSuffix returns the suffix of icon files in the icons directory
+func (o *Options) iconSuffix() string {
+	if o.IconSuffix == "" {
+		return imageSuffix
+	}
+	return o.IconSuffix
+}
+
+// isExcluded checks if the resource type isn't shown by ExcludeTypes, IncludeTypes or View
+func (o *Options) isExcluded(resType string) bool {
+	if contains(o.ExcludeTypes, resType) {
+		return true
+	}
+	if len(o.IncludeTypes) > 0 {
+		return !contains(o.IncludeTypes, resType)
+	}
+	return o.View != "" && !contains(viewTypes[o.View], resType)
+}
+
+// views returns the sorted list of views
+func views() []string {
+	list := []string{}
+	for v := range viewTypes {
+		list = append(list, v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// warningOut returns the writer of the warnings
+func (o *Options) warningOut() io.Writer {
+	if o.WarningOut == nil {
+		return os.Stderr
+	}
+	return o.WarningOut
+}
+
+// theme returns the theme of the graph
+func (o *Options) theme() *Theme {
+	if o.Theme == nil {
+		return DefaultTheme()
+	}
+	return o.Theme
+}
+
+// ranks returns the ordered list of ranks of resource types
+func (o *Options) ranks() []string {
+	if len(o.Ranks) == 0 {
+		return resources.ResourceTypes
+	}
+	return o.Ranks
+}
+
+// resourceTypes returns all the resource types shown in the graph
+func resourceTypes() []string {
+	types := []string{}
+	for _, rankRes := range resources.ResourceTypes {
+		types = append(types, strings.Fields(rankRes)...)
+	}
+	return types
+}
+
+// isResourceType checks if t is one of the resource types shown in the graph
+func isResourceType(t string) bool {
+	return contains(resourceTypes(), t)
+}
+
+// contains checks if list has s
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import "testing"
+
+func TestValidateFontSize(t *testing.T) {
+	tests := []struct {
+		fontSize float64
+		wantErr  string
+	}{
+		{fontSize: 0},
+		{fontSize: 16},
+		{fontSize: -1, wantErr: "invalid font size -1, must not be negative (0 for the default of graphviz)"},
+	}
+
+	for _, tt := range tests {
+		err := (&Options{FontSize: tt.fontSize}).Validate()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("font size %v: unexpected error: %v", tt.fontSize, err)
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("font size %v: error = %v, want %q", tt.fontSize, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// scheduleMacros is the map of the predefined schedules of cronjobs to their descriptions
+	scheduleMacros = map[string]string{
+		"@yearly":   "yearly",
+		"@annually": "yearly",
+		"@monthly":  "monthly",
+		"@weekly":   "weekly",
+		"@daily":    "daily",
+		"@midnight": "daily",
+		"@hourly":   "hourly",
+	}
+
+	// weekdays is the list of the names of days of week in cron expressions, from Sunday as 0
+	weekdays = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+)
+
+// describeSchedule returns the human-readable description of the schedule of a cronjob
+// ex) every 5 minutes, every 2 hours at minute 0, daily at 03:00, on Monday through Friday at 09:30
+// Only the common schedules are described, and ok is false for the others,
+// like the ones with months or time zones, which are shown as they are.
+func describeSchedule(schedule string) (desc string, ok bool) {
+	if desc, ok := scheduleMacros[strings.TrimSpace(schedule)]; ok {
+		return desc, true
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return "", false
+	}
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if month != "*" {
+		return "", false
+	}
+
+	// Schedules run every hour or more often, like "*/5 * * * *"
+	if hour == "*" && dom == "*" && dow == "*" {
+		switch {
+		case minute == "*" || minute == "*/1":
+			return "every minute", true
+		case strings.HasPrefix(minute, "*/"):
+			if n, ok := cronNumber(strings.TrimPrefix(minute, "*/"), 1, 59); ok {
+				return fmt.Sprintf("every %d minutes", n), true
+			}
+		default:
+			if m, ok := cronNumber(minute, 0, 59); ok {
+				return fmt.Sprintf("hourly at minute %d", m), true
+			}
+		}
+		return "", false
+	}
+
+	// Schedules run at a time on some days, like "30 9 * * 1-5"
+	m, ok := cronNumber(minute, 0, 59)
+	if !ok {
+		return "", false
+	}
+	if strings.HasPrefix(hour, "*/") && dom == "*" && dow == "*" {
+		if n, ok := cronNumber(strings.TrimPrefix(hour, "*/"), 2, 23); ok {
+			return fmt.Sprintf("every %d hours at minute %d", n, m), true
+		}
+		return "", false
+	}
+	h, ok := cronNumber(hour, 0, 23)
+	if !ok {
+		return "", false
+	}
+	at := fmt.Sprintf("%02d:%02d", h, m)
+	switch {
+	case dom == "*" && dow == "*":
+		return "daily at " + at, true
+	case dom == "*":
+		if days, ok := describeWeekdays(dow); ok {
+			return fmt.Sprintf("on %s at %s", days, at), true
+		}
+	case dow == "*":
+		if d, ok := cronNumber(dom, 1, 31); ok {
+			return fmt.Sprintf("monthly on day %d at %s", d, at), true
+		}
+	}
+	return "", false
+}
+
+// describeWeekdays returns the description of the days of week in a cron expression
+// ex) Monday for "1", Monday through Friday for "1-5", Saturday, Sunday for "6,0"
+func describeWeekdays(dow string) (string, bool) {
+	names := []string{}
+	for _, part := range strings.Split(dow, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		from, ok := cronWeekday(bounds[0])
+		if !ok {
+			return "", false
+		}
+		if len(bounds) == 1 {
+			names = append(names, weekdays[from])
+			continue
+		}
+		to, ok := cronWeekday(bounds[1])
+		if !ok || to <= from {
+			return "", false
+		}
+		names = append(names, weekdays[from]+" through "+weekdays[to])
+	}
+	return strings.Join(names, ", "), true
+}
+
+// cronWeekday returns the index of the day of week in weekdays, which is a number from 0 to 7 or a name like "MON"
+// 7 is also Sunday, as in cron.
+func cronWeekday(s string) (int, bool) {
+	for i, name := range weekdays {
+		if strings.EqualFold(s, name[:3]) {
+			return i, true
+		}
+	}
+	n, ok := cronNumber(s, 0, 7)
+	return n % 7, ok
+}
+
+// cronNumber returns the number in a field of a cron expression, if it is between min and max
+func cronNumber(s string, min, max int) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, false
+	}
+	return n, true
+}
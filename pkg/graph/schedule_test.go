@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import "testing"
+
+func TestDescribeSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     string
+		wantOK   bool
+	}{
+		// Macros
+		{schedule: "@daily", want: "daily", wantOK: true},
+		{schedule: "@midnight", want: "daily", wantOK: true},
+		{schedule: "@annually", want: "yearly", wantOK: true},
+		{schedule: "@hourly", want: "hourly", wantOK: true},
+		// Every hour or more often
+		{schedule: "* * * * *", want: "every minute", wantOK: true},
+		{schedule: "*/1 * * * *", want: "every minute", wantOK: true},
+		{schedule: "*/5 * * * *", want: "every 5 minutes", wantOK: true},
+		{schedule: "15 * * * *", want: "hourly at minute 15", wantOK: true},
+		{schedule: "0 */2 * * *", want: "every 2 hours at minute 0", wantOK: true},
+		// Daily, weekly and monthly
+		{schedule: "0 3 * * *", want: "daily at 03:00", wantOK: true},
+		{schedule: "30 9 * * 1-5", want: "on Monday through Friday at 09:30", wantOK: true},
+		{schedule: "0 10 * * 6,0", want: "on Saturday, Sunday at 10:00", wantOK: true},
+		{schedule: "0 10 * * SUN", want: "on Sunday at 10:00", wantOK: true},
+		{schedule: "0 9 * * 7", want: "on Sunday at 09:00", wantOK: true},
+		{schedule: "0 0 1 * *", want: "monthly on day 1 at 00:00", wantOK: true},
+		// Rejected
+		{schedule: ""},
+		{schedule: "CRON_TZ=UTC 0 3 * * *"},
+		{schedule: "0 3 * 1 *"},
+		{schedule: "0 9 * * 5-1"},
+		{schedule: "60 * * * *"},
+		{schedule: "*/0 * * * *"},
+		{schedule: "0 0 1 * 1"},
+		{schedule: "0 3 * *"},
+	}
+
+	for _, tt := range tests {
+		got, ok := describeSchedule(tt.schedule)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("describeSchedule(%q) = (%q, %v), want (%q, %v)", tt.schedule, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
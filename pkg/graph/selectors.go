@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"sort"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// podIndex is the index of the pods in a namespace by their labels
+// It finds the pods matching selectors without checking the labels of all
+// the pods for each selector, which is slow for namespaces with many
+// services and pods.
+type podIndex struct {
+	// names and labels are the ones of the pods in the order of resources
+	names  []string
+	labels []labels.Set
+	// byLabel is the map of label key to label value to the indices of the pods with the label
+	byLabel map[string]map[string][]int
+	// byKey is the map of label key to the indices of the pods with the key
+	byKey map[string][]int
+}
+
+// newPodIndex returns the index of the pods in res
+// Indices of the pods are kept in ascending order for each label.
+func newPodIndex(res *resources.Resources) *podIndex {
+	idx := &podIndex{byLabel: map[string]map[string][]int{}, byKey: map[string][]int{}}
+	for i, pod := range res.Pods.Items {
+		idx.names = append(idx.names, pod.Name)
+		idx.labels = append(idx.labels, labels.Set(pod.GetLabels()))
+		for k, v := range pod.GetLabels() {
+			if idx.byLabel[k] == nil {
+				idx.byLabel[k] = map[string][]int{}
+			}
+			idx.byLabel[k][v] = append(idx.byLabel[k][v], i)
+			idx.byKey[k] = append(idx.byKey[k], i)
+		}
+	}
+
+	return idx
+}
+
+// candidates returns the indices of the pods that may match req in ascending order
+// It returns false if req can't narrow down the pods, like NotIn and DoesNotExist.
+func (idx *podIndex) candidates(req labels.Requirement) ([]int, bool) {
+	switch req.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		values := req.Values().List()
+		if len(values) == 1 {
+			return idx.byLabel[req.Key()][values[0]], true
+		}
+		// Pods are never duplicated, as each pod has only one value for the key
+		merged := []int{}
+		for _, v := range values {
+			merged = append(merged, idx.byLabel[req.Key()][v]...)
+		}
+		sort.Ints(merged)
+		return merged, true
+	case selection.Exists:
+		return idx.byKey[req.Key()], true
+	}
+
+	return nil, false
+}
+
+// matchedPodNames returns the names of the pods whose labels match sel, in the order of resources
+// Only the pods that may match the requirement of sel with the fewest
+// candidates are checked. All the pods are checked, if no requirement
+// narrows down them, like empty selectors.
+func (idx *podIndex) matchedPodNames(sel labels.Selector) []string {
+	var candidates []int
+	narrowed := false
+	if reqs, selectable := sel.Requirements(); selectable {
+		for _, req := range reqs {
+			if c, ok := idx.candidates(req); ok && (!narrowed || len(c) < len(candidates)) {
+				candidates, narrowed = c, true
+			}
+		}
+	}
+
+	names := []string{}
+	if !narrowed {
+		for i, name := range idx.names {
+			if sel.Matches(idx.labels[i]) {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	for _, i := range candidates {
+		if sel.Matches(idx.labels[i]) {
+			names = append(names, idx.names[i])
+		}
+	}
+
+	return names
+}
+
+// matchedPodNames returns the names of the pods in res whose labels match sel
+// The index of the pods is built at the first call for each namespace.
+func (g *Graph) matchedPodNames(res *resources.Resources, sel labels.Selector) []string {
+	idx, ok := g.podIndexes[res.Namespace]
+	if !ok {
+		idx = newPodIndex(res)
+		g.podIndexes[res.Namespace] = idx
+	}
+
+	return idx.matchedPodNames(sel)
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// resourcesWithPods returns Resources with numPods pods spread over numApps apps
+// Each pod has labels app=app-<n> and tier=frontend or tier=backend.
+func resourcesWithPods(numPods, numApps int) *resources.Resources {
+	res := &resources.Resources{Namespace: "default", Pods: &corev1.PodList{}}
+	for i := 0; i < numPods; i++ {
+		tier := "frontend"
+		if i%2 == 1 {
+			tier = "backend"
+		}
+		res.Pods.Items = append(res.Pods.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      fmt.Sprintf("pod-%d", i),
+			Labels:    map[string]string{"app": fmt.Sprintf("app-%d", i%numApps), "tier": tier},
+		}})
+	}
+	return res
+}
+
+// scanPodNames returns the names of the pods in res whose labels match sel, checking all the pods
+func scanPodNames(res *resources.Resources, sel labels.Selector) []string {
+	names := []string{}
+	for _, pod := range res.Pods.Items {
+		if sel.Matches(labels.Set(pod.GetLabels())) {
+			names = append(names, pod.Name)
+		}
+	}
+	return names
+}
+
+func TestPodIndexMatchedPodNames(t *testing.T) {
+	res := resourcesWithPods(50, 10)
+	idx := newPodIndex(res)
+
+	for _, s := range []string{
+		"",
+		"app=app-1",
+		"app=app-1,tier=backend",
+		"app in (app-1, app-2)",
+		"app in (app-1, app-2),tier=frontend",
+		"tier",
+		"app!=app-1",
+		"app notin (app-1),tier=frontend",
+		"!tier",
+		"app=unknown",
+	} {
+		sel, err := labels.Parse(s)
+		if err != nil {
+			t.Fatalf("failed to parse selector %q: %v", s, err)
+		}
+		if got, want := idx.matchedPodNames(sel), scanPodNames(res, sel); !reflect.DeepEqual(got, want) {
+			t.Errorf("selector %q: got %v, want %v", s, got, want)
+		}
+	}
+}
+
+func BenchmarkPodIndex(b *testing.B) {
+	res := resourcesWithPods(500, 100)
+	selectors := []labels.Selector{}
+	for i := 0; i < 100; i++ {
+		selectors = append(selectors, labels.SelectorFromSet(labels.Set{"app": fmt.Sprintf("app-%d", i), "tier": "frontend"}))
+	}
+
+	b.Run("index", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			idx := newPodIndex(res)
+			for _, sel := range selectors {
+				idx.matchedPodNames(sel)
+			}
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, sel := range selectors {
+				scanPodNames(res, sel)
+			}
+		}
+	})
+}
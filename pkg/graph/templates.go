@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// podTemplateSuffix is appended to the names of workloads for the names of the pods made from their templates
+	// ex) my-deployment-template
+	podTemplateSuffix = "-template"
+)
+
+// workloadTemplate represents the pod template of a workload resource
+type workloadTemplate struct {
+	resType  string
+	kind     string
+	name     string
+	obj      metav1.Object
+	template *corev1.PodTemplateSpec
+}
+
+// withPodTemplates returns the copy of res with the pods made from the templates of the workloads without pods
+// They are shown as ghost pods with the box for pod templates, to show the
+// resources that the workloads would use, like deployments scaled to zero.
+// Only the workloads whose controllers aren't in res have ghost pods, like
+// deployments rather than their replicasets, and the ghost pods are owned by
+// the workloads directly. res isn't changed, and the other lists than pods
+// are shared with the copy.
+func (g *Graph) withPodTemplates(res *resources.Resources) *resources.Resources {
+	hasPods := ownersWithPods(res)
+	pods := append([]corev1.Pod{}, res.Pods.Items...)
+	for _, w := range workloadTemplates(res) {
+		if w.template == nil || hasPods[w.resType+"/"+w.name] || hasControllerIn(res, w.obj) {
+			continue
+		}
+		isController := true
+		pod := corev1.Pod{ObjectMeta: *w.template.ObjectMeta.DeepCopy(), Spec: *w.template.Spec.DeepCopy()}
+		pod.Name = w.name + podTemplateSuffix
+		pod.Namespace = res.Namespace
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: w.kind, Name: w.name, Controller: &isController}}
+		pods = append(pods, pod)
+		g.podTemplates[res.Namespace+"/"+pod.Name] = true
+	}
+
+	copied := *res
+	copied.Pods = &corev1.PodList{Items: pods}
+	return &copied
+}
+
+// workloadTemplates returns the pod templates of the workload resources in res
+// Templates of deploymentconfigs that can't be decoded are nil.
+func workloadTemplates(res *resources.Resources) []workloadTemplate {
+	templates := []workloadTemplate{}
+	for i := range res.Deploys.Items {
+		o := &res.Deploys.Items[i]
+		templates = append(templates, workloadTemplate{"deploy", "Deployment", o.Name, o, &o.Spec.Template})
+	}
+	for i := range res.Stss.Items {
+		o := &res.Stss.Items[i]
+		templates = append(templates, workloadTemplate{"sts", "StatefulSet", o.Name, o, &o.Spec.Template})
+	}
+	for i := range res.Dss.Items {
+		o := &res.Dss.Items[i]
+		templates = append(templates, workloadTemplate{"ds", "DaemonSet", o.Name, o, &o.Spec.Template})
+	}
+	for i := range res.Rss.Items {
+		o := &res.Rss.Items[i]
+		templates = append(templates, workloadTemplate{"rs", "ReplicaSet", o.Name, o, &o.Spec.Template})
+	}
+	for i := range res.Rcs.Items {
+		o := &res.Rcs.Items[i]
+		templates = append(templates, workloadTemplate{"rc", "ReplicationController", o.Name, o, o.Spec.Template})
+	}
+	for i := range res.Jobs.Items {
+		o := &res.Jobs.Items[i]
+		templates = append(templates, workloadTemplate{"job", "Job", o.Name, o, &o.Spec.Template})
+	}
+	for i := range res.CronJobs.Items {
+		o := &res.CronJobs.Items[i]
+		templates = append(templates, workloadTemplate{"cronjob", "CronJob", o.Name, o, &o.Spec.JobTemplate.Spec.Template})
+	}
+	for i := range res.DeploymentConfigs.Items {
+		o := &res.DeploymentConfigs.Items[i]
+		templates = append(templates, workloadTemplate{"dc", "DeploymentConfig", o.GetName(), o, deploymentConfigTemplate(o)})
+	}
+
+	return templates
+}
+
+// deploymentConfigTemplate returns the pod template in spec.template of the deploymentconfig
+// It returns nil if the template isn't found or can't be decoded.
+func deploymentConfigTemplate(dc *unstructured.Unstructured) *corev1.PodTemplateSpec {
+	m, ok, _ := unstructured.NestedMap(dc.Object, "spec", "template")
+	if !ok {
+		return nil
+	}
+	template := &corev1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, template); err != nil {
+		return nil
+	}
+	return template
+}
+
+// ownersWithPods returns the set of the controllers of the pods in res, including the controllers of the controllers
+// Keys are "resType/name", like "deploy/my-deployment" for the pods of its replicasets.
+func ownersWithPods(res *resources.Resources) map[string]bool {
+	owners := map[string]bool{}
+	for i := range res.Pods.Items {
+		var obj metav1.Object = &res.Pods.Items[i]
+		for {
+			ref := metav1.GetControllerOf(obj)
+			if ref == nil {
+				break
+			}
+			kind, err := resources.NormalizeResource(ref.Kind)
+			if err != nil {
+				// Skip resource that isn't available for this tool, like CRD
+				break
+			}
+			key := kind + "/" + ref.Name
+			if owners[key] {
+				// Controllers of this one are already added
+				break
+			}
+			owners[key] = true
+			owner, err := meta.Accessor(res.GetResource(kind, ref.Name))
+			if err != nil {
+				break
+			}
+			obj = owner
+		}
+	}
+
+	return owners
+}
+
+// hasControllerIn returns true if the controller of obj is in res, like the deployment of a replicaset
+func hasControllerIn(res *resources.Resources, obj metav1.Object) bool {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		return false
+	}
+	kind, err := resources.NormalizeResource(ref.Kind)
+	return err == nil && res.HasResource(kind, ref.Name)
+}
+
+// isPodTemplate returns true if the pod is made from the template of a workload by ShowPodTemplates in options
+func (g *Graph) isPodTemplate(namespace, name string) bool {
+	return g.podTemplates[namespace+"/"+name]
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Style is the appearance of nodes or edges in graphviz
+type Style struct {
+	// Color is the color of the line, like "red" or "#ff0000".
+	// The default color of graphviz is used if empty.
+	Color string
+	// Style is the style of the line, like "dashed" or "rounded,dashed".
+	// The solid line is used if empty.
+	Style string
+}
+
+// Theme is the appearance of the nodes and the edges of the graph
+// The directions of edges aren't in the theme, as they show how resources
+// are related. The zero value of Style draws solid lines with the default color.
+type Theme struct {
+	// Styles of the edges for each relation of resources
+	// OwnerReference is for the owner with controller: true, and
+	// NonControllerOwnerReference is for the other owners.
+	OwnerReference              Style
+	NonControllerOwnerReference Style
+	// Volume is for pvcs and the volumes that aren't k8s resources used by pods
+	Volume Style
+	// Config is for configmaps and secrets used by pods
+	Config         Style
+	ServiceAccount Style
+	// ServiceSelector is for pods selected by services, via selectors or endpoints
+	ServiceSelector Style
+	// NotReadyEndpoint is for pods that aren't ready in endpoints of services
+	NotReadyEndpoint  Style
+	IngressBackend    Style
+	ExternalName      Style
+	NetworkPolicy     Style
+	NetworkPolicyPeer Style
+	DisruptionBudget  Style
+	ScaleTarget       Style
+	// NodePlacement is for the nodes that pods run on
+	NodePlacement Style
+	// GroupMember is for resources in the groups by the annotation
+	GroupMember Style
+	// Reference is for the other references, like pvc to pv and rolebinding to role
+	Reference Style
+
+	// Styles of the boxes of nodes, which have no box if not listed below
+	// Namespace is for the clusters of namespaces.
+	Namespace          Style
+	ClusterScoped      Style
+	HeadlessService    Style
+	OrphanedReplicaSet Style
+	// PodTemplate is for the ghost pods made from the templates of workloads without pods
+	PodTemplate Style
+	// ExternalVolume is for the volumes that aren't k8s resources, like hostPath
+	ExternalVolume Style
+	// ExternalHost is for the hosts outside the cluster that ExternalName services point to
+	ExternalHost Style
+	// Placeholder is for the resources in the namespaces that aren't shown
+	Placeholder Style
+	// Group is for the groups by the annotation
+	Group Style
+
+	// TypeColors is the colors of the rounded boxes of nodes for each resource
+	// type, like "svc": "blue", to distinguish the types at a glance.
+	// Nodes of the types not in the map have no box.
+	TypeColors map[string]string
+	// PodPhaseColors is the background colors of pod names for each phase,
+	// which have no color if not in the map.
+	PodPhaseColors map[corev1.PodPhase]string
+	// SuspendedCronJobColor is the background color of the names of suspended cronjobs
+	SuspendedCronJobColor string
+	// ChangeColors is the colors of nodes and edges for each change status in diff graphs
+	ChangeColors map[resources.ChangeStatus]string
+}
+
+// DefaultTheme returns the theme used if no theme is specified by options
+// The returned theme can be modified to customize a part of the appearance.
+func DefaultTheme() *Theme {
+	return &Theme{
+		OwnerReference:              Style{Style: "dashed"},
+		NonControllerOwnerReference: Style{Color: "gray", Style: "dotted"},
+		ServiceAccount:              Style{Style: "dashed"},
+		NotReadyEndpoint:            Style{Color: "red", Style: "dashed"},
+		NetworkPolicy:               Style{Color: "red"},
+		NetworkPolicyPeer:           Style{Color: "red", Style: "dashed"},
+		DisruptionBudget:            Style{Color: "purple"},
+		ScaleTarget:                 Style{Color: "blue"},
+		NodePlacement:               Style{Color: "gray"},
+		GroupMember:                 Style{Color: "darkgreen", Style: "dotted"},
+
+		Namespace:          Style{Style: "dotted"},
+		ClusterScoped:      Style{Style: "dashed"},
+		HeadlessService:    Style{Style: "dotted"},
+		OrphanedReplicaSet: Style{Color: "orange", Style: "dashed"},
+		PodTemplate:        Style{Color: "gray", Style: "dashed"},
+		ExternalVolume:     Style{Color: "gray", Style: "rounded,dashed"},
+		ExternalHost:       Style{Color: "gray", Style: "rounded,dashed"},
+		Placeholder:        Style{Color: "gray", Style: "dashed"},
+		Group:              Style{Color: "gray", Style: "rounded"},
+
+		TypeColors: map[string]string{
+			"hpa":          "blue",
+			"netpol":       "red",
+			"pdb":          "purple",
+			"deploy":       "darkorange",
+			"dc":           "darkorange",
+			"cronjob":      "darkorange",
+			"sts":          "darkorange",
+			"ds":           "darkorange",
+			"rs":           "darkorange",
+			"rc":           "darkorange",
+			"job":          "darkorange",
+			"pod":          "forestgreen",
+			"pvc":          "sienna",
+			"cm":           "goldenrod",
+			"secret":       "goldenrod",
+			"sa":           "slategray",
+			"svc":          "royalblue",
+			"rolebinding":  "slategray",
+			"ing":          "mediumpurple",
+			"route":        "mediumpurple",
+			"role":         "slategray",
+			"ingressclass": "mediumpurple",
+			"pv":           "sienna",
+			"clusterrole":  "slategray",
+			"storageclass": "sienna",
+			"node":         "gray",
+			"vs":           "sienna",
+			"vsc":          "sienna",
+		},
+		PodPhaseColors: map[corev1.PodPhase]string{
+			corev1.PodRunning: "palegreen",
+			corev1.PodPending: "yellow",
+			corev1.PodFailed:  "tomato",
+			corev1.PodUnknown: "tomato",
+		},
+		SuspendedCronJobColor: "lightgray",
+		ChangeColors: map[resources.ChangeStatus]string{
+			resources.ChangeAdded:     "green",
+			resources.ChangeRemoved:   "red",
+			resources.ChangeUnchanged: "gray",
+		},
+	}
+}
+
+// attrs sets the color and the style to attrs, and returns it
+// A new map is returned if attrs is nil. Values that can't be used as
+// graphviz IDs as they are, like "rounded,dashed", are quoted.
+// ex) {"dir": "back"} -> {"color": "red", "dir": "back", "style": "dashed"}
+func (s Style) attrs(attrs map[string]string) map[string]string {
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	if s.Color != "" {
+		attrs["color"] = quoteAttr(s.Color)
+	}
+	if s.Style != "" {
+		attrs["style"] = quoteAttr(s.Style)
+	}
+	return attrs
+}
+
+// quoteAttr returns the value of the attribute quoted, if it has characters that need quotes
+// Values already quoted and HTML labels, like "<<B>k8s</B>>", are returned as they are.
+func quoteAttr(v string) string {
+	if strings.HasPrefix(v, "\"") || strings.HasPrefix(v, "<") {
+		return v
+	}
+	if invalidIDChars.MatchString(v) {
+		return fmt.Sprintf("%q", v)
+	}
+	return v
+}
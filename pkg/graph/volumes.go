@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// Types of the nodes for volumes that aren't k8s resources
+	volumeTypeHostPath = "hostpath"
+	volumeTypeCSI      = "csi"
+)
+
+// externalVolume represents a volume of pods that isn't a k8s resource
+type externalVolume struct {
+	// volType is the type of the volume, like "hostpath"
+	volType string
+	// name is the path for hostPath volumes and the driver for CSI volumes
+	name string
+	// label is shown as the label of the node, like "hostPath: /var/log"
+	label string
+}
+
+// externalVolumes returns the volumes of the pod that aren't k8s resources
+func externalVolumes(pod *corev1.Pod) []externalVolume {
+	vols := []externalVolume{}
+	for _, vol := range pod.Spec.Volumes {
+		switch {
+		case vol.VolumeSource.HostPath != nil:
+			path := vol.VolumeSource.HostPath.Path
+			vols = append(vols, externalVolume{volType: volumeTypeHostPath, name: path, label: "hostPath: " + path})
+		case vol.VolumeSource.CSI != nil:
+			driver := vol.VolumeSource.CSI.Driver
+			vols = append(vols, externalVolume{volType: volumeTypeCSI, name: driver, label: "csi: " + driver})
+		}
+	}
+	return vols
+}
+
+// generateVolumeNodes creates the nodes for the volumes of pods in res that aren't k8s resources
+// ```
+// hostpath_my_namespace___2fvar_2flog [ color=gray, label="hostPath: /var/log", shape=box, style="rounded,dashed" ];
+// ```
+// They are placed in the same rank as pvcs, with rounded dashed gray box.
+// Only the volumes of the pods that have nodes are created, not to leave
+// the ones of hidden or excluded pods without edges.
+func (g *Graph) generateVolumeNodes(res *resources.Resources) {
+	rank := g.rankOf("pvc")
+	for _, pod := range res.Pods.Items {
+		if !g.hasNode[g.resourceName(res.Namespace, "pod", pod.Name)] {
+			continue
+		}
+		for _, vol := range externalVolumes(&pod) {
+			id := g.volumeName(res.Namespace, vol.volType, vol.name)
+			if g.hasNode[id] {
+				continue
+			}
+
+			attrs := g.theme.ExternalVolume.attrs(map[string]string{"label": fmt.Sprintf("%q", vol.label), "shape": "box"})
+			g.nodes = append(g.nodes, node{id: id, namespace: res.Namespace, resType: vol.volType, name: vol.name, rank: g.rankName(res.Namespace, rank), attrs: attrs})
+			g.hasNode[id] = true
+		}
+	}
+}
+
+// genExternalVolumePodRef generates the edges of Pod to the volumes that aren't k8s resources
+func (g *Graph) genExternalVolumePodRef(res *resources.Resources) {
+	// Add edge if below matches:
+	//   - v1.Pod.spec.volumes[].hostPath.path
+	//     v1.Pod.spec.volumes[].csi.driver
+	// ```
+	// pod_my_namespace__my_pod->hostpath_my_namespace___2fvar_2flog[ dir=none ];
+	// ```
+	for _, pod := range res.Pods.Items {
+		for _, vol := range externalVolumes(&pod) {
+			g.addEdge(edgeKindVolume, g.resourceName(res.Namespace, "pod", pod.Name), g.volumeName(res.Namespace, vol.volType, vol.name),
+				g.theme.Volume.attrs(map[string]string{"dir": "none"}))
+		}
+	}
+}
+
+// volumeName returns the id of the node for the volume that isn't a k8s resource
+// Volumes are shared by the pods in the same namespace that have the same path or driver.
+// The characters that can't be used in ids are escaped as "_" with their hex
+// codes, including "_" itself, so different paths like /var/log and /var_log
+// never share the node.
+// ex) hostpath_my_namespace___2fvar_2flog
+func (g *Graph) volumeName(namespace, volType, name string) string {
+	escaped := invalidIDChars.ReplaceAllStringFunc(strings.ReplaceAll(name, "_", "_5f"), func(c string) string {
+		return fmt.Sprintf("_%x", c)
+	})
+	return volType + "_" + g.escapeName(namespace) + "__" + escaped
+}
+
+// rankOf returns the index of the rank that has resType
+// It returns the last rank, if no rank has resType.
+func (g *Graph) rankOf(resType string) int {
+	ranks := g.opts.ranks()
+	for r, rankRes := range ranks {
+		if contains(strings.Fields(rankRes), resType) {
+			return r
+		}
+	}
+	return len(ranks) - 1
+}
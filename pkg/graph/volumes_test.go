@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// volumesManifest has a running pod and a succeeded pod with hostPath and CSI volumes
+const volumesManifest = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: running
+  namespace: default
+spec:
+  volumes:
+  - name: log
+    hostPath:
+      path: /var/log
+  - name: other-log
+    hostPath:
+      path: /var_log
+status:
+  phase: Running
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: succeeded
+  namespace: default
+spec:
+  volumes:
+  - name: data
+    hostPath:
+      path: /data
+  - name: secrets
+    csi:
+      driver: secrets-store.csi.k8s.io
+status:
+  phase: Succeeded
+`
+
+// dotWithOptions returns the graph of res generated with opts in dot format
+func dotWithOptions(t *testing.T, manifest string, opts Options) string {
+	t.Helper()
+
+	g, err := NewGraphWithOptions(resourcesFromManifest(t, "default", manifest), iconsDir, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.WriteDot(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+// hasDotNode checks if dot has the node of id
+func hasDotNode(dot, id string) bool {
+	return regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(id) + ` \[`).MatchString(dot)
+}
+
+func TestExternalVolumesOfHiddenPods(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		want     []string
+		wantNone []string
+	}{
+		{
+			name: "all pods",
+			opts: Options{ShowExternalVolumes: true},
+			want: []string{"hostpath_default___2fvar_2flog", "hostpath_default___2fdata", "csi_default__secrets_2dstore_2ecsi_2ek8s_2eio"},
+		},
+		{
+			name:     "succeeded pods hidden",
+			opts:     Options{ShowExternalVolumes: true, HideSucceededPods: true},
+			want:     []string{"hostpath_default___2fvar_2flog"},
+			wantNone: []string{"hostpath_default___2fdata", "csi_default__secrets_2dstore_2ecsi_2ek8s_2eio"},
+		},
+		{
+			name:     "pods excluded",
+			opts:     Options{ShowExternalVolumes: true, ExcludeTypes: []string{"pod"}},
+			wantNone: []string{"hostpath_default___2fvar_2flog", "hostpath_default___2fdata", "csi_default__secrets_2dstore_2ecsi_2ek8s_2eio"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dot := dotWithOptions(t, volumesManifest, tt.opts)
+			for _, id := range tt.want {
+				if !hasDotNode(dot, id) {
+					t.Errorf("node %s not found:\n%s", id, dot)
+				}
+			}
+			for _, id := range tt.wantNone {
+				if hasDotNode(dot, id) {
+					t.Errorf("node %s found, want none:\n%s", id, dot)
+				}
+			}
+		})
+	}
+}
+
+func TestExternalVolumesOfSimilarPaths(t *testing.T) {
+	dot := dotWithOptions(t, volumesManifest, Options{ShowExternalVolumes: true})
+
+	for id, label := range map[string]string{
+		"hostpath_default___2fvar_2flog": "hostPath: /var/log",
+		"hostpath_default___2fvar_5flog": "hostPath: /var_log",
+	} {
+		if !hasDotNode(dot, id) {
+			t.Errorf("node %s not found:\n%s", id, dot)
+		}
+		if !strings.Contains(dot, `"`+label+`"`) {
+			t.Errorf("label %q not found:\n%s", label, dot)
+		}
+		if got := countEdges(dot, "pod_default__running", id); got != 1 {
+			t.Errorf("got %d edges from pod to %s, want 1:\n%s", got, id, dot)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+)
+
+// Warning represents a problem found on generating or plotting the graph, which doesn't stop it
+type Warning struct {
+	// Namespace, Kind and Name are the ones of the resource that has the
+	// problem, like the pod referring to a pvc that isn't found. Kind is the
+	// resource type, like "pod". Namespace is empty for the resource looked
+	// up in all namespaces, like the one to focus on, and all of them are
+	// empty for the problems that aren't about resources, like missing icons.
+	Namespace string
+	Kind      string
+	Name      string
+	// Message describes the problem
+	// ex) pvc my-pvc not found as a volume for pod my-pod
+	Message string
+}
+
+// String returns the message of the warning
+func (w Warning) String() string {
+	return w.Message
+}
+
+// Warnings returns the warnings on generating the graph, and plotting it so far
+// They are also written to WarningOut in options when they are found.
+func (g *Graph) Warnings() []Warning {
+	return g.warnings
+}
+
+// warnf records the warning formatted with format and args, which isn't about a resource
+func (g *Graph) warnf(format string, args ...interface{}) {
+	g.addWarning(Warning{Message: fmt.Sprintf(format, args...)})
+}
+
+// resourceWarnf records the warning formatted with format and args, about the resource of kind and name in namespace
+func (g *Graph) resourceWarnf(namespace, kind, name, format string, args ...interface{}) {
+	g.addWarning(Warning{Namespace: namespace, Kind: kind, Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+// addWarning records w, and writes its message to warnOut
+func (g *Graph) addWarning(w Warning) {
+	g.warnings = append(g.warnings, w)
+	fmt.Fprintln(g.warnOut, w.Message)
+}
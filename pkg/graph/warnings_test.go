@@ -0,0 +1,271 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// defaultServiceAccount is the manifest of the service account used by pods without serviceAccountName
+// It is added to the manifests of all the tests, not to warn on it.
+const defaultServiceAccount = `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: default
+  namespace: default
+`
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		opts     Options
+		want     []Warning
+	}{
+		{
+			name: "no warning",
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  volumes:
+  - name: config
+    configMap:
+      name: config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: default
+`,
+		},
+		{
+			name: "dangling pvc",
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  volumes:
+  - name: data
+    persistentVolumeClaim:
+      claimName: data
+`,
+			want: []Warning{{Namespace: "default", Kind: "pod", Name: "web", Message: "pvc data not found as a volume for pod web"}},
+		},
+		{
+			name: "unknown configmap",
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  containers:
+  - name: web
+    envFrom:
+    - configMapRef:
+        name: config
+`,
+			want: []Warning{{Namespace: "default", Kind: "pod", Name: "web", Message: "cm config not found as a reference for pod web"}},
+		},
+		{
+			name: "unknown secret",
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  volumes:
+  - name: tls
+    secret:
+      secretName: tls
+`,
+			want: []Warning{{Namespace: "default", Kind: "pod", Name: "web", Message: "secret tls not found as a reference for pod web"}},
+		},
+		{
+			name: "unknown service account",
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  serviceAccountName: robot
+`,
+			want: []Warning{{Namespace: "default", Kind: "pod", Name: "web", Message: "sa robot not found as a service account for pod web"}},
+		},
+		{
+			name: "unknown owner",
+			manifest: `
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: web-1
+  namespace: default
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: web
+    uid: "1"
+    controller: true
+`,
+			want: []Warning{{Namespace: "default", Kind: "rs", Name: "web-1", Message: "deploy web not found as a owner refernce for rs web-1"}},
+		},
+		{
+			name: "unknown pv and storageclass",
+			manifest: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: default
+spec:
+  volumeName: pv-1
+  storageClassName: fast
+`,
+			want: []Warning{
+				{Namespace: "default", Kind: "pvc", Name: "data", Message: "pv pv-1 not found as a volume for pvc data"},
+				{Namespace: "default", Kind: "pvc", Name: "data", Message: "storageclass fast not found for pvc data"},
+			},
+		},
+		{
+			name: "unknown role and subject",
+			manifest: `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: admin
+  namespace: default
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: admin
+subjects:
+- kind: ServiceAccount
+  name: robot
+`,
+			want: []Warning{
+				{Namespace: "default", Kind: "rolebinding", Name: "admin", Message: "role admin not found as a role for rolebinding admin"},
+				{Namespace: "default", Kind: "rolebinding", Name: "admin", Message: "sa robot not found as a subject for rolebinding admin"},
+			},
+		},
+		{
+			name: "networkpolicy without pods",
+			manifest: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny
+  namespace: default
+spec:
+  podSelector:
+    matchLabels:
+      app: web
+`,
+			want: []Warning{{Namespace: "default", Kind: "netpol", Name: "deny", Message: "no pod matches podSelector for netpol deny"}},
+		},
+		{
+			name: "unknown ingress backend and class",
+			manifest: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: default
+spec:
+  ingressClassName: nginx
+  defaultBackend:
+    service:
+      name: web
+      port:
+        number: 80
+`,
+			want: []Warning{
+				{Namespace: "default", Kind: "ing", Name: "web", Message: "svc web not found for ingress web"},
+				{Namespace: "default", Kind: "ing", Name: "web", Message: "ingressclass nginx not found for ingress web"},
+			},
+		},
+		{
+			name: "unknown scale target",
+			manifest: `
+apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+  namespace: default
+spec:
+  maxReplicas: 2
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: web
+`,
+			want: []Warning{{Namespace: "default", Kind: "hpa", Name: "web", Message: "deploy web not found as a scale target for hpa web"}},
+		},
+		{
+			name: "unknown node",
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  nodeName: node-1
+`,
+			opts: Options{ShowNodes: true},
+			want: []Warning{{Namespace: "default", Kind: "pod", Name: "web", Message: "node node-1 not found as the node that pod web runs on"}},
+		},
+		{
+			name: "unknown resource to focus on",
+			opts: Options{Focus: "deploy/web"},
+			want: []Warning{{Kind: "deploy", Name: "web", Message: "deploy web not found to focus on"}},
+		},
+		{
+			name: "missing icon",
+			opts: Options{Icons: map[string]string{"pod": "/nonexistent/pod.png"}},
+			want: []Warning{{Message: "icon /nonexistent/pod.png for pod not found, using the default icon: stat /nonexistent/pod.png: no such file or directory"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := resourcesFromManifest(t, "default", tt.manifest+"---"+defaultServiceAccount)
+			out := &bytes.Buffer{}
+			opts := tt.opts
+			opts.WarningOut = out
+
+			g, err := NewGraphWithOptions(res, iconsDir, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := g.Warnings()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %#v, want %#v", got, tt.want)
+			}
+
+			wantOut := ""
+			for _, w := range tt.want {
+				wantOut += w.Message + "\n"
+			}
+			if out.String() != wantOut {
+				t.Errorf("written warnings = %q, want %q", out.String(), wantOut)
+			}
+		})
+	}
+}
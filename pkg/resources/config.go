@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// NewConfig returns the config to access the k8s cluster
+// The config is resolved in below order, like kubectl:
+//   - kubeconfig file, which must exist if specified
+//   - kubeconfig files in KUBECONFIG environment variable
+//   - ~/.kube/config
+//   - in-cluster config, which uses the service account token mounted to the pod
+// In-cluster config is used only if no kubeconfig is found. The kubeconfig is
+// loaded with the same rules as --kubeconfig and --context options of
+// kubectl, but the default server of kubectl, localhost:8080, isn't used.
+func NewConfig(kubeconfig string) (*rest.Config, error) {
+	return NewConfigForContext(kubeconfig, "")
+}
+
+// NewConfigForContext returns the config to access the k8s cluster of the context in kubeconfig
+// The kubeconfig is resolved in the same order as NewConfig, and the current
+// context is used if kubeContext is empty, like --context option of kubectl.
+// In-cluster config can't be used if kubeContext is specified.
+func NewConfigForContext(kubeconfig, kubeContext string) (*rest.Config, error) {
+	config, err := clientConfig(kubeconfig, kubeContext).ClientConfig()
+	if clientcmd.IsEmptyConfig(err) {
+		config, err = rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("no kubeconfig found in %s environment variable or %s, and not running in a cluster: %v", clientcmd.RecommendedConfigPathEnvVar, clientcmd.RecommendedHomeFile, err)
+		}
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	return config, nil
+}
+
+// NewResourcesFromKubeconfig returns Resources for the namespace got from the cluster of the kubeconfig file at path
+// See NewResourcesFromKubeconfigContext for details.
+func NewResourcesFromKubeconfig(path, namespace string) (*Resources, error) {
+	return NewResourcesFromKubeconfigContext(context.Background(), path, namespace)
+}
+
+// NewResourcesFromKubeconfigContext returns Resources for the namespace got from the cluster of the kubeconfig file at path
+// The current context in the kubeconfig is used. If path is empty, the
+// kubeconfig files in KUBECONFIG environment variable are used, or
+// ~/.kube/config if it isn't set, like kubectl. Unlike NewConfig, it fails if
+// the file at path doesn't exist, and in-cluster config isn't used.
+// ctx is used for the requests to get k8s resources, and the first failure
+// is returned in the same way as FetchResources.
+func NewResourcesFromKubeconfigContext(ctx context.Context, path, namespace string) (*Resources, error) {
+	return NewResourcesFromKubeconfigWithOptions(ctx, path, namespace, FetchOptions{})
+}
+
+// NewResourcesFromKubeconfigWithOptions returns Resources for the namespace got with opts from the cluster of the kubeconfig file at path
+// The kubeconfig is loaded in the same way as NewResourcesFromKubeconfigContext.
+// Failures to load it are always returned, but the ones to get resources are
+// reported and skipped if ContinueOnError is set in opts.
+func NewResourcesFromKubeconfigWithOptions(ctx context.Context, path, namespace string, opts FetchOptions) (*Resources, error) {
+	clientset, err := clientsetFromKubeconfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return FetchResourcesWithOptions(ctx, clientset, namespace, opts)
+}
+
+// clientsetFromKubeconfig returns the clientset for the cluster of the current context in the kubeconfig file at path
+// The default kubeconfig files are used if path is empty.
+func clientsetFromKubeconfig(path string) (kubernetes.Interface, error) {
+	desc := "default kubeconfig"
+	if path != "" {
+		desc = fmt.Sprintf("kubeconfig %q", path)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %v", desc, err)
+		}
+	}
+
+	config, err := clientConfig(path, "").ClientConfig()
+	if clientcmd.IsEmptyConfig(err) {
+		return nil, fmt.Errorf("failed to load %s: no kubeconfig found in %s environment variable or %s", desc, clientcmd.RecommendedConfigPathEnvVar, clientcmd.RecommendedHomeFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", desc, err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client from %s: %v", desc, err)
+	}
+
+	return clientset, nil
+}
+
+// CurrentNamespace returns the namespace of the current context in kubeconfig
+// The kubeconfig is resolved in the same order as NewConfig, and "default" is
+// returned if the context has no namespace or no kubeconfig is found, like kubectl.
+func CurrentNamespace(kubeconfig string) (string, error) {
+	return ContextNamespace(kubeconfig, "")
+}
+
+// ContextNamespace returns the namespace of the context in kubeconfig
+// The current context is used if kubeContext is empty, in the same way as CurrentNamespace.
+func ContextNamespace(kubeconfig, kubeContext string) (string, error) {
+	ns, _, err := clientConfig(kubeconfig, kubeContext).Namespace()
+	if clientcmd.IsEmptyConfig(err) {
+		return metav1.NamespaceDefault, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get namespace of the context: %v", err)
+	}
+
+	return ns, nil
+}
+
+// CurrentContext returns the name of the current context in kubeconfig
+// The kubeconfig is resolved in the same order as NewConfig, and empty
+// string is returned if no kubeconfig is found, like in a cluster.
+func CurrentContext(kubeconfig string) (string, error) {
+	config, err := clientConfig(kubeconfig, "").RawConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the current context: %v", err)
+	}
+
+	return config.CurrentContext, nil
+}
+
+// clientConfig returns the client config from kubeconfig with the context overridden by kubeContext
+// The current context is used if kubeContext is empty.
+func clientConfig(kubeconfig, kubeContext string) clientcmd.ClientConfig {
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules(kubeconfig), overrides)
+}
+
+// loadingRules returns the rules to load kubeconfig
+// kubeconfig is used if specified, which fails to load if it doesn't exist,
+// and the default rules are used otherwise.
+func loadingRules(kubeconfig string) *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	return rules
+}
@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// writeKubeconfig writes the kubeconfig with contexts "a" and "b" to the file named name in dir, and returns its path
+// The clusters of the contexts have servers serverA and serverB, and the
+// current context is "a".
+func writeKubeconfig(t *testing.T, dir, name, serverA, serverB string) string {
+	t.Helper()
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: a
+  cluster:
+    server: %[1]s
+- name: b
+  cluster:
+    server: %[2]s
+users:
+- name: user
+  user:
+    token: token
+contexts:
+- name: a
+  context:
+    cluster: a
+    user: user
+- name: b
+  context:
+    cluster: b
+    user: user
+current-context: a
+`, serverA, serverB)
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setHomeKubeconfig makes path the kubeconfig in the home directory during the test
+// The kubeconfig of the user running the test isn't used then.
+func setHomeKubeconfig(t *testing.T, path string) {
+	t.Helper()
+
+	home := clientcmd.RecommendedHomeFile
+	clientcmd.RecommendedHomeFile = path
+	t.Cleanup(func() { clientcmd.RecommendedHomeFile = home })
+}
+
+func TestNewConfigForContext(t *testing.T) {
+	dir := t.TempDir()
+	explicit := writeKubeconfig(t, dir, "explicit", "https://explicit-a", "https://explicit-b")
+	env := writeKubeconfig(t, dir, "env", "https://env-a", "https://env-b")
+	home := writeKubeconfig(t, dir, "home", "https://home-a", "https://home-b")
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name        string
+		kubeconfig  string
+		kubeContext string
+		envVar      string
+		home        string
+		wantHost    string
+		wantErr     string
+	}{
+		{name: "existing file wins over KUBECONFIG", kubeconfig: explicit, envVar: env, home: home, wantHost: "https://explicit-a"},
+		{name: "context in existing file", kubeconfig: explicit, kubeContext: "b", envVar: env, wantHost: "https://explicit-b"},
+		{name: "missing file", kubeconfig: missing, envVar: env, home: home, wantErr: "no such file or directory"},
+		{name: "KUBECONFIG wins over home", envVar: env, home: home, wantHost: "https://env-a"},
+		{name: "context in KUBECONFIG", kubeContext: "b", envVar: env, wantHost: "https://env-b"},
+		{name: "home if KUBECONFIG is unset", home: home, wantHost: "https://home-a"},
+		{name: "context in home", kubeContext: "b", home: home, wantHost: "https://home-b"},
+		{name: "context without kubeconfig", kubeContext: "b", wantErr: `context "b" does not exist`},
+		{name: "no kubeconfig out of cluster", wantErr: "not running in a cluster"},
+		{name: "unknown context", kubeconfig: explicit, kubeContext: "c", wantErr: `context "c" does not exist`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(clientcmd.RecommendedConfigPathEnvVar, tt.envVar)
+			// In-cluster config is found by the environment variables of the service
+			t.Setenv("KUBERNETES_SERVICE_HOST", "")
+			t.Setenv("KUBERNETES_SERVICE_PORT", "")
+			homeFile := tt.home
+			if homeFile == "" {
+				homeFile = missing
+			}
+			setHomeKubeconfig(t, homeFile)
+
+			config, err := NewConfigForContext(tt.kubeconfig, tt.kubeContext)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("expected error, got config for %q", config.Host)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %q, want %q in it", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Host != tt.wantHost {
+				t.Errorf("host = %q, want %q", config.Host, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestNewResourcesFromKubeconfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid")
+	if err := ioutil.WriteFile(invalid, []byte("apiVersion: v1\nkind: Config\ncurrent-context: missing\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		envVar  string
+		wantErr string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing"), wantErr: "failed to load kubeconfig"},
+		{name: "invalid context", path: invalid, wantErr: `context was not found for specified context: missing`},
+		{name: "no default kubeconfig", envVar: filepath.Join(dir, "missing"), wantErr: "failed to load default kubeconfig: no kubeconfig found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(clientcmd.RecommendedConfigPathEnvVar, tt.envVar)
+			setHomeKubeconfig(t, filepath.Join(dir, "missing"))
+
+			_, err := NewResourcesFromKubeconfig(tt.path, "default")
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want %q in it", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewResourcesFromKubeconfigWithOptions(t *testing.T) {
+	// The server forbids all the requests, like the user without any permission
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`)
+	}))
+	defer server.Close()
+	path := writeKubeconfig(t, t.TempDir(), "forbidden", server.URL, server.URL)
+
+	if _, err := NewResourcesFromKubeconfigWithOptions(context.Background(), path, "default", FetchOptions{}); err == nil {
+		t.Error("expected error without ContinueOnError, got nil")
+	}
+
+	var res *Resources
+	var err error
+	stderr := captureStderr(t, func() {
+		res, err = NewResourcesFromKubeconfigWithOptions(context.Background(), path, "default", FetchOptions{ContinueOnError: true})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error with ContinueOnError: %v", err)
+	}
+	if got := res.GetResourceNames("pod"); len(got) != 0 {
+		t.Errorf("pods = %v, want none", got)
+	}
+	if !strings.Contains(stderr, "Failed to get clusterroles") {
+		t.Errorf("stderr = %q, want warning on clusterroles", stderr)
+	}
+}
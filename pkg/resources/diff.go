@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+)
+
+// ChangeStatus represents how a k8s resource is changed between two snapshots
+type ChangeStatus string
+
+const (
+	// ChangeAdded is for the resource only in the new snapshot
+	ChangeAdded ChangeStatus = "added"
+	// ChangeRemoved is for the resource only in the old snapshot
+	ChangeRemoved ChangeStatus = "removed"
+	// ChangeUnchanged is for the resource in both snapshots
+	ChangeUnchanged ChangeStatus = "unchanged"
+)
+
+// Changes represents the differences between two snapshots of k8s resources in a namespace
+type Changes struct {
+	// Old and New are the snapshots compared
+	Old *Resources
+	New *Resources
+	// Merged has the resources in New and the resources removed from Old,
+	// so that the removed resources can be shown with the others.
+	Merged *Resources
+
+	statuses map[string]ChangeStatus
+}
+
+// Diff compares old and new snapshots of k8s resources by resource types and names
+// Only the existence of resources is compared, and the resources in both
+// snapshots are unchanged even if their specs are changed.
+// It returns error if old and new are for different namespaces.
+func Diff(old, new *Resources) (*Changes, error) {
+	if old.Namespace != new.Namespace {
+		return nil, fmt.Errorf("namespaces to compare differ: %q and %q", old.Namespace, new.Namespace)
+	}
+
+	c := &Changes{Old: old, New: new, Merged: newEmptyResources(new.Namespace), statuses: map[string]ChangeStatus{}}
+	for _, rankRes := range ResourceTypes {
+		for _, kind := range strings.Fields(rankRes) {
+			for _, name := range new.GetResourceNames(kind) {
+				c.statuses[kind+"/"+name] = ChangeAdded
+				if old.HasResource(kind, name) {
+					c.statuses[kind+"/"+name] = ChangeUnchanged
+				}
+				if err := c.Merged.addObject(new.GetResource(kind, name).DeepCopyObject()); err != nil {
+					return nil, err
+				}
+			}
+			for _, name := range old.GetResourceNames(kind) {
+				if new.HasResource(kind, name) {
+					continue
+				}
+				c.statuses[kind+"/"+name] = ChangeRemoved
+				if err := c.Merged.addObject(old.GetResource(kind, name).DeepCopyObject()); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// Endpoints of the services removed are kept, to find pods behind them
+	c.Merged.Endpoints.Items = append(c.Merged.Endpoints.Items, new.Endpoints.Items...)
+	for _, ep := range old.Endpoints.Items {
+		if c.Status("svc", ep.Name) == ChangeRemoved {
+			c.Merged.Endpoints.Items = append(c.Merged.Endpoints.Items, ep)
+		}
+	}
+	c.Merged.EndpointSlices.Items = append(c.Merged.EndpointSlices.Items, new.EndpointSlices.Items...)
+	for _, slice := range old.EndpointSlices.Items {
+		if c.Status("svc", slice.Labels[discoveryv1beta1.LabelServiceName]) == ChangeRemoved {
+			c.Merged.EndpointSlices.Items = append(c.Merged.EndpointSlices.Items, slice)
+		}
+	}
+
+	return c, nil
+}
+
+// Status returns how the resource of kind and name is changed
+// Empty status is returned if the resource is in neither of the snapshots.
+func (c *Changes) Status(kind, name string) ChangeStatus {
+	return c.statuses[kind+"/"+name]
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// resourcesFromManifest returns Resources for the namespace read from the manifest
+func resourcesFromManifest(t *testing.T, namespace, manifest string) *Resources {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := ioutil.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := NewResourcesFromManifests(path, namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// diffManifest has a service web with its endpoints and endpointslice, and pods web-1 and db
+// The service and pod web-1 are missing in the new snapshot of the test,
+// and pod web-2 is added instead.
+const diffManifest = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+---
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web
+  namespace: default
+---
+apiVersion: discovery.k8s.io/v1beta1
+kind: EndpointSlice
+metadata:
+  name: web-abcde
+  namespace: default
+  labels:
+    kubernetes.io/service-name: web
+addressType: IPv4
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-1
+  namespace: default
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: default
+`
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name               string
+		old                string
+		new                string
+		wantStatuses       map[string]ChangeStatus
+		wantMerged         map[string][]string
+		wantEndpoints      []string
+		wantEndpointSlices []string
+	}{
+		{
+			name: "same snapshots",
+			old:  diffManifest,
+			new:  diffManifest,
+			wantStatuses: map[string]ChangeStatus{
+				"svc/web": ChangeUnchanged, "pod/web-1": ChangeUnchanged, "pod/db": ChangeUnchanged,
+			},
+			wantMerged:         map[string][]string{"svc": {"web"}, "pod": {"db", "web-1"}},
+			wantEndpoints:      []string{"web"},
+			wantEndpointSlices: []string{"web-abcde"},
+		},
+		{
+			name: "added and removed",
+			old:  diffManifest,
+			new: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-2
+  namespace: default
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db
+  namespace: default
+`,
+			wantStatuses: map[string]ChangeStatus{
+				"svc/web": ChangeRemoved, "pod/web-1": ChangeRemoved, "pod/web-2": ChangeAdded, "pod/db": ChangeUnchanged,
+				"pod/unknown": "",
+			},
+			wantMerged: map[string][]string{"svc": {"web"}, "pod": {"db", "web-1", "web-2"}},
+			// Kept for the removed service
+			wantEndpoints:      []string{"web"},
+			wantEndpointSlices: []string{"web-abcde"},
+		},
+		{
+			name: "all added",
+			new:  diffManifest,
+			wantStatuses: map[string]ChangeStatus{
+				"svc/web": ChangeAdded, "pod/web-1": ChangeAdded, "pod/db": ChangeAdded,
+			},
+			wantMerged:         map[string][]string{"svc": {"web"}, "pod": {"db", "web-1"}},
+			wantEndpoints:      []string{"web"},
+			wantEndpointSlices: []string{"web-abcde"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Diff(resourcesFromManifest(t, "default", tt.old), resourcesFromManifest(t, "default", tt.new))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for key, want := range tt.wantStatuses {
+				kindName := strings.SplitN(key, "/", 2)
+				if got := c.Status(kindName[0], kindName[1]); got != want {
+					t.Errorf("status of %s = %q, want %q", key, got, want)
+				}
+			}
+			for kind, want := range tt.wantMerged {
+				got := c.Merged.GetResourceNames(kind)
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("merged %s = %v, want %v", kind, got, want)
+				}
+			}
+			endpoints := []string{}
+			for _, ep := range c.Merged.Endpoints.Items {
+				endpoints = append(endpoints, ep.Name)
+			}
+			if !reflect.DeepEqual(endpoints, tt.wantEndpoints) {
+				t.Errorf("merged endpoints = %v, want %v", endpoints, tt.wantEndpoints)
+			}
+			slices := []string{}
+			for _, slice := range c.Merged.EndpointSlices.Items {
+				slices = append(slices, slice.Name)
+			}
+			if !reflect.DeepEqual(slices, tt.wantEndpointSlices) {
+				t.Errorf("merged endpointslices = %v, want %v", slices, tt.wantEndpointSlices)
+			}
+		})
+	}
+}
+
+func TestDiffNamespaces(t *testing.T) {
+	if _, err := Diff(newEmptyResources("old"), newEmptyResources("new")); err == nil {
+		t.Error("expected error for different namespaces, got nil")
+	}
+}
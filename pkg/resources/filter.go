@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// FilterByLabelSelector drops k8s resources whose labels don't match selector
+// selector has the same format as the one for kubectl, like "app=frontend".
+// Empty selector keeps all resources.
+func (r *Resources) FilterByLabelSelector(selector string) error {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid label selector %q: %v", selector, err)
+	}
+	if sel.Empty() {
+		return nil
+	}
+
+	r.filter(func(o metav1.Object) bool {
+		return sel.Matches(labels.Set(o.GetLabels()))
+	})
+
+	return nil
+}
+
+// FilterByName drops k8s resources whose names don't match pattern
+// pattern is a regular expression, like "^api-", which matches a part of
+// names unless anchored. Empty pattern keeps all resources.
+func (r *Resources) FilterByName(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid name pattern %q: %v", pattern, err)
+	}
+
+	r.filter(func(o metav1.Object) bool {
+		return re.MatchString(o.GetName())
+	})
+
+	return nil
+}
+
+// FilterByCreationTimestamp drops k8s resources created before since
+// Resources without creation timestamp, like the ones read from manifests,
+// are kept, as they aren't created yet. Zero since keeps all resources.
+func (r *Resources) FilterByCreationTimestamp(since time.Time) {
+	if since.IsZero() {
+		return
+	}
+
+	r.filter(func(o metav1.Object) bool {
+		created := o.GetCreationTimestamp()
+		return created.IsZero() || !created.Time.Before(since)
+	})
+}
+
+// filter drops k8s resources for which keep returns false
+func (r *Resources) filter(keep func(metav1.Object) bool) {
+	svcs := r.Svcs.Items[:0]
+	for _, o := range r.Svcs.Items {
+		if keep(&o) {
+			svcs = append(svcs, o)
+		}
+	}
+	r.Svcs.Items = svcs
+
+	pvcs := r.Pvcs.Items[:0]
+	for _, o := range r.Pvcs.Items {
+		if keep(&o) {
+			pvcs = append(pvcs, o)
+		}
+	}
+	r.Pvcs.Items = pvcs
+
+	cms := r.Cms.Items[:0]
+	for _, o := range r.Cms.Items {
+		if keep(&o) {
+			cms = append(cms, o)
+		}
+	}
+	r.Cms.Items = cms
+
+	secrets := r.Secrets.Items[:0]
+	for _, o := range r.Secrets.Items {
+		if keep(&o) {
+			secrets = append(secrets, o)
+		}
+	}
+	r.Secrets.Items = secrets
+
+	sas := r.Sas.Items[:0]
+	for _, o := range r.Sas.Items {
+		if keep(&o) {
+			sas = append(sas, o)
+		}
+	}
+	r.Sas.Items = sas
+
+	pods := r.Pods.Items[:0]
+	for _, o := range r.Pods.Items {
+		if keep(&o) {
+			pods = append(pods, o)
+		}
+	}
+	r.Pods.Items = pods
+
+	stss := r.Stss.Items[:0]
+	for _, o := range r.Stss.Items {
+		if keep(&o) {
+			stss = append(stss, o)
+		}
+	}
+	r.Stss.Items = stss
+
+	dss := r.Dss.Items[:0]
+	for _, o := range r.Dss.Items {
+		if keep(&o) {
+			dss = append(dss, o)
+		}
+	}
+	r.Dss.Items = dss
+
+	rss := r.Rss.Items[:0]
+	for _, o := range r.Rss.Items {
+		if keep(&o) {
+			rss = append(rss, o)
+		}
+	}
+	r.Rss.Items = rss
+
+	rcs := r.Rcs.Items[:0]
+	for _, o := range r.Rcs.Items {
+		if keep(&o) {
+			rcs = append(rcs, o)
+		}
+	}
+	r.Rcs.Items = rcs
+
+	deploys := r.Deploys.Items[:0]
+	for _, o := range r.Deploys.Items {
+		if keep(&o) {
+			deploys = append(deploys, o)
+		}
+	}
+	r.Deploys.Items = deploys
+
+	jobs := r.Jobs.Items[:0]
+	for _, o := range r.Jobs.Items {
+		if keep(&o) {
+			jobs = append(jobs, o)
+		}
+	}
+	r.Jobs.Items = jobs
+
+	cronJobs := r.CronJobs.Items[:0]
+	for _, o := range r.CronJobs.Items {
+		if keep(&o) {
+			cronJobs = append(cronJobs, o)
+		}
+	}
+	r.CronJobs.Items = cronJobs
+
+	ingresses := r.Ingresses.Items[:0]
+	for _, o := range r.Ingresses.Items {
+		if keep(&o) {
+			ingresses = append(ingresses, o)
+		}
+	}
+	r.Ingresses.Items = ingresses
+
+	hpas := r.Hpas.Items[:0]
+	for _, o := range r.Hpas.Items {
+		if keep(&o) {
+			hpas = append(hpas, o)
+		}
+	}
+	r.Hpas.Items = hpas
+
+	ingressClasses := r.IngressClasses.Items[:0]
+	for _, o := range r.IngressClasses.Items {
+		if keep(&o) {
+			ingressClasses = append(ingressClasses, o)
+		}
+	}
+	r.IngressClasses.Items = ingressClasses
+
+	networkPolicies := r.NetworkPolicies.Items[:0]
+	for _, o := range r.NetworkPolicies.Items {
+		if keep(&o) {
+			networkPolicies = append(networkPolicies, o)
+		}
+	}
+	r.NetworkPolicies.Items = networkPolicies
+
+	pdbs := r.Pdbs.Items[:0]
+	for _, o := range r.Pdbs.Items {
+		if keep(&o) {
+			pdbs = append(pdbs, o)
+		}
+	}
+	r.Pdbs.Items = pdbs
+
+	pvs := r.Pvs.Items[:0]
+	for _, o := range r.Pvs.Items {
+		if keep(&o) {
+			pvs = append(pvs, o)
+		}
+	}
+	r.Pvs.Items = pvs
+
+	storageClasses := r.StorageClasses.Items[:0]
+	for _, o := range r.StorageClasses.Items {
+		if keep(&o) {
+			storageClasses = append(storageClasses, o)
+		}
+	}
+	r.StorageClasses.Items = storageClasses
+
+	roles := r.Roles.Items[:0]
+	for _, o := range r.Roles.Items {
+		if keep(&o) {
+			roles = append(roles, o)
+		}
+	}
+	r.Roles.Items = roles
+
+	roleBindings := r.RoleBindings.Items[:0]
+	for _, o := range r.RoleBindings.Items {
+		if keep(&o) {
+			roleBindings = append(roleBindings, o)
+		}
+	}
+	r.RoleBindings.Items = roleBindings
+
+	clusterRoles := r.ClusterRoles.Items[:0]
+	for _, o := range r.ClusterRoles.Items {
+		if keep(&o) {
+			clusterRoles = append(clusterRoles, o)
+		}
+	}
+	r.ClusterRoles.Items = clusterRoles
+
+	deploymentConfigs := r.DeploymentConfigs.Items[:0]
+	for _, o := range r.DeploymentConfigs.Items {
+		if keep(&o) {
+			deploymentConfigs = append(deploymentConfigs, o)
+		}
+	}
+	r.DeploymentConfigs.Items = deploymentConfigs
+
+	routes := r.Routes.Items[:0]
+	for _, o := range r.Routes.Items {
+		if keep(&o) {
+			routes = append(routes, o)
+		}
+	}
+	r.Routes.Items = routes
+
+	nodes := r.Nodes.Items[:0]
+	for _, o := range r.Nodes.Items {
+		if keep(&o) {
+			nodes = append(nodes, o)
+		}
+	}
+	r.Nodes.Items = nodes
+
+	volumeSnapshots := r.VolumeSnapshots.Items[:0]
+	for _, o := range r.VolumeSnapshots.Items {
+		if keep(&o) {
+			volumeSnapshots = append(volumeSnapshots, o)
+		}
+	}
+	r.VolumeSnapshots.Items = volumeSnapshots
+
+	volumeSnapshotContents := r.VolumeSnapshotContents.Items[:0]
+	for _, o := range r.VolumeSnapshotContents.Items {
+		if keep(&o) {
+			volumeSnapshotContents = append(volumeSnapshotContents, o)
+		}
+	}
+	r.VolumeSnapshotContents.Items = volumeSnapshotContents
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	v1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// convertIngressList converts extensions/v1beta1 IngressList to networking.k8s.io/v1 one
+func convertIngressList(in *v1beta1.IngressList) *networkingv1.IngressList {
+	out := &networkingv1.IngressList{ListMeta: in.ListMeta}
+	for i := range in.Items {
+		out.Items = append(out.Items, convertIngress(&in.Items[i]))
+	}
+	return out
+}
+
+// convertIngress converts extensions/v1beta1 Ingress to networking.k8s.io/v1 one
+// Only fields that have the same meaning in both versions are converted.
+func convertIngress(in *v1beta1.Ingress) networkingv1.Ingress {
+	out := networkingv1.Ingress{
+		ObjectMeta: in.ObjectMeta,
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: in.Spec.IngressClassName,
+			DefaultBackend:   convertIngressBackend(in.Spec.Backend),
+		},
+		Status: networkingv1.IngressStatus{LoadBalancer: in.Status.LoadBalancer},
+	}
+	out.Kind = "Ingress"
+	out.APIVersion = networkingv1.SchemeGroupVersion.String()
+
+	for _, tls := range in.Spec.TLS {
+		out.Spec.TLS = append(out.Spec.TLS, networkingv1.IngressTLS{Hosts: tls.Hosts, SecretName: tls.SecretName})
+	}
+	for _, rule := range in.Spec.Rules {
+		r := networkingv1.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			r.HTTP = &networkingv1.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				p := networkingv1.HTTPIngressPath{Path: path.Path}
+				if path.PathType != nil {
+					pathType := networkingv1.PathType(*path.PathType)
+					p.PathType = &pathType
+				}
+				if backend := convertIngressBackend(&path.Backend); backend != nil {
+					p.Backend = *backend
+				}
+				r.HTTP.Paths = append(r.HTTP.Paths, p)
+			}
+		}
+		out.Spec.Rules = append(out.Spec.Rules, r)
+	}
+
+	return out
+}
+
+// convertIngressBackend converts extensions/v1beta1 IngressBackend to networking.k8s.io/v1 one
+func convertIngressBackend(in *v1beta1.IngressBackend) *networkingv1.IngressBackend {
+	if in == nil {
+		return nil
+	}
+
+	out := &networkingv1.IngressBackend{Resource: in.Resource}
+	if in.ServiceName != "" {
+		out.Service = &networkingv1.IngressServiceBackend{Name: in.ServiceName}
+		if in.ServicePort.Type == intstr.String {
+			out.Service.Port.Name = in.ServicePort.StrVal
+		} else {
+			out.Service.Port.Number = in.ServicePort.IntVal
+		}
+	}
+
+	return out
+}
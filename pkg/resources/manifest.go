@@ -0,0 +1,332 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	v1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	// manifestExts represents the file extensions read as manifests in a directory
+	manifestExts = []string{".yaml", ".yml", ".json"}
+)
+
+// NewResourcesFromManifests returns Resources for the namespace read from manifests
+// path is a manifest file or a directory that has manifest files.
+// Each file can have multiple documents separated by "---".
+// Resources without namespace are handled as the ones in the namespace,
+// and resources in other namespaces or of unknown kinds are ignored.
+func NewResourcesFromManifests(path, namespace string) (*Resources, error) {
+	res := newEmptyResources(namespace)
+
+	files, err := manifestFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if err := res.addManifestFile(file); err != nil {
+			return nil, fmt.Errorf("failed to read manifest %q: %v", file, err)
+		}
+	}
+
+	return res, nil
+}
+
+// NewResourcesFromJSON returns Resources for the namespace read from JSON, like the output of kubectl
+// reader has a list, like the output of "kubectl get all,ingress,pvc -o json",
+// or a stream of concatenated objects. Resources are handled in the same way
+// as NewResourcesFromManifests.
+func NewResourcesFromJSON(reader io.Reader, namespace string) (*Resources, error) {
+	res := newEmptyResources(namespace)
+	if err := res.addJSON(reader); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// newEmptyResources returns Resources for the namespace with no k8s resources
+func newEmptyResources(namespace string) *Resources {
+	return &Resources{
+		Namespace:         namespace,
+		Svcs:              &corev1.ServiceList{},
+		Pvcs:              &corev1.PersistentVolumeClaimList{},
+		Cms:               &corev1.ConfigMapList{},
+		Secrets:           &corev1.SecretList{},
+		Sas:               &corev1.ServiceAccountList{},
+		Pods:              &corev1.PodList{},
+		Stss:              &appsv1.StatefulSetList{},
+		Dss:               &appsv1.DaemonSetList{},
+		Rss:               &appsv1.ReplicaSetList{},
+		Rcs:               &corev1.ReplicationControllerList{},
+		Deploys:           &appsv1.DeploymentList{},
+		Jobs:              &batchv1.JobList{},
+		CronJobs:          &batchv1beta1.CronJobList{},
+		Ingresses:         &networkingv1.IngressList{},
+		Hpas:              &autoscalingv1.HorizontalPodAutoscalerList{},
+		NetworkPolicies:   &networkingv1.NetworkPolicyList{},
+		Pdbs:              &policyv1beta1.PodDisruptionBudgetList{},
+		Roles:             &rbacv1.RoleList{},
+		RoleBindings:      &rbacv1.RoleBindingList{},
+		Endpoints:         &corev1.EndpointsList{},
+		EndpointSlices:    &discoveryv1beta1.EndpointSliceList{},
+		DeploymentConfigs: &unstructured.UnstructuredList{},
+		Routes:            &unstructured.UnstructuredList{},
+		VolumeSnapshots:   &unstructured.UnstructuredList{},
+		IngressClasses:    &networkingv1.IngressClassList{},
+		Pvs:               &corev1.PersistentVolumeList{},
+		StorageClasses:    &storagev1.StorageClassList{},
+		ClusterRoles:      &rbacv1.ClusterRoleList{},
+		Nodes:             &corev1.NodeList{},
+
+		VolumeSnapshotContents: &unstructured.UnstructuredList{},
+	}
+}
+
+// manifestFiles returns the manifest files in path
+// path itself is returned if it is a file.
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, ext := range manifestExts {
+			if !info.IsDir() && strings.ToLower(filepath.Ext(p)) == ext {
+				files = append(files, p)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// addManifestFile adds k8s resources in the manifest file to r
+// JSON files are read as streams of objects, as concatenated objects
+// aren't separated by "---".
+func (r *Resources) addManifestFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		return r.addJSON(f)
+	}
+
+	reader := yaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		if err := r.addDocument(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// addJSON adds k8s resources in the stream of JSON objects to r
+func (r *Resources) addJSON(reader io.Reader) error {
+	decoder := json.NewDecoder(reader)
+	for {
+		var doc json.RawMessage
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.addDocument(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// addDocument adds the k8s resource in the document of YAML or JSON to r
+// Items of lists, like List of kubectl and PodList, are added one by one.
+func (r *Resources) addDocument(doc []byte) error {
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(doc, nil, nil)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) {
+			// OpenShift resources and CSI volume snapshots aren't registered, as they are unstructured
+			if obj, ok := decodeUnstructuredObject(doc); ok {
+				return r.addObject(obj)
+			}
+			// Skip resource that isn't available for this tool, like CRD
+			return nil
+		}
+		return err
+	}
+
+	if meta.IsListType(obj) {
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			// Items of List aren't decoded, as their kinds are unknown
+			if unknown, ok := item.(*runtime.Unknown); ok {
+				if err := r.addDocument(unknown.Raw); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := r.addObject(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return r.addObject(obj)
+}
+
+// decodeUnstructuredObject decodes the manifest of a resource handled as an unstructured object
+// They are OpenShift resources and CSI volume snapshots. It returns false if
+// the manifest isn't for them.
+func decodeUnstructuredObject(doc []byte) (*unstructured.Unstructured, bool) {
+	data, err := yaml.ToJSON(doc)
+	if err != nil {
+		return nil, false
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		return nil, false
+	}
+	switch obj.GroupVersionKind().GroupKind() {
+	case deploymentConfigKind, routeKind, volumeSnapshotKind, volumeSnapshotContentKind:
+		return obj, true
+	}
+
+	return nil, false
+}
+
+// addObject adds the k8s resource to r, if it is in the namespace of r
+func (r *Resources) addObject(obj runtime.Object) error {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if m.GetNamespace() != "" && m.GetNamespace() != r.Namespace {
+		return nil
+	}
+	m.SetNamespace(r.Namespace)
+
+	switch o := obj.(type) {
+	case *corev1.Service:
+		r.Svcs.Items = append(r.Svcs.Items, *o)
+	case *corev1.PersistentVolumeClaim:
+		r.Pvcs.Items = append(r.Pvcs.Items, *o)
+	case *corev1.ConfigMap:
+		r.Cms.Items = append(r.Cms.Items, *o)
+	case *corev1.Secret:
+		r.Secrets.Items = append(r.Secrets.Items, *o)
+	case *corev1.ServiceAccount:
+		r.Sas.Items = append(r.Sas.Items, *o)
+	case *corev1.Pod:
+		r.Pods.Items = append(r.Pods.Items, *o)
+	case *appsv1.StatefulSet:
+		r.Stss.Items = append(r.Stss.Items, *o)
+	case *appsv1.DaemonSet:
+		r.Dss.Items = append(r.Dss.Items, *o)
+	case *appsv1.ReplicaSet:
+		r.Rss.Items = append(r.Rss.Items, *o)
+	case *corev1.ReplicationController:
+		r.Rcs.Items = append(r.Rcs.Items, *o)
+	case *appsv1.Deployment:
+		r.Deploys.Items = append(r.Deploys.Items, *o)
+	case *batchv1.Job:
+		r.Jobs.Items = append(r.Jobs.Items, *o)
+	case *batchv1beta1.CronJob:
+		r.CronJobs.Items = append(r.CronJobs.Items, *o)
+	case *networkingv1.Ingress:
+		r.Ingresses.Items = append(r.Ingresses.Items, *o)
+	case *v1beta1.Ingress:
+		r.Ingresses.Items = append(r.Ingresses.Items, convertIngress(o))
+	case *autoscalingv1.HorizontalPodAutoscaler:
+		r.Hpas.Items = append(r.Hpas.Items, *o)
+	case *networkingv1.NetworkPolicy:
+		r.NetworkPolicies.Items = append(r.NetworkPolicies.Items, *o)
+	case *policyv1beta1.PodDisruptionBudget:
+		r.Pdbs.Items = append(r.Pdbs.Items, *o)
+	case *rbacv1.Role:
+		r.Roles.Items = append(r.Roles.Items, *o)
+	case *rbacv1.RoleBinding:
+		r.RoleBindings.Items = append(r.RoleBindings.Items, *o)
+	case *corev1.Endpoints:
+		r.Endpoints.Items = append(r.Endpoints.Items, *o)
+	case *discoveryv1beta1.EndpointSlice:
+		r.EndpointSlices.Items = append(r.EndpointSlices.Items, *o)
+	case *networkingv1.IngressClass:
+		r.IngressClasses.Items = append(r.IngressClasses.Items, *o)
+	case *corev1.PersistentVolume:
+		r.Pvs.Items = append(r.Pvs.Items, *o)
+	case *storagev1.StorageClass:
+		r.StorageClasses.Items = append(r.StorageClasses.Items, *o)
+	case *rbacv1.ClusterRole:
+		r.ClusterRoles.Items = append(r.ClusterRoles.Items, *o)
+	case *corev1.Node:
+		r.Nodes.Items = append(r.Nodes.Items, *o)
+	case *unstructured.Unstructured:
+		switch o.GroupVersionKind().GroupKind() {
+		case deploymentConfigKind:
+			r.DeploymentConfigs.Items = append(r.DeploymentConfigs.Items, *o)
+		case routeKind:
+			r.Routes.Items = append(r.Routes.Items, *o)
+		case volumeSnapshotKind:
+			r.VolumeSnapshots.Items = append(r.VolumeSnapshots.Items, *o)
+		case volumeSnapshotContentKind:
+			r.VolumeSnapshotContents.Items = append(r.VolumeSnapshotContents.Items, *o)
+		}
+	}
+
+	return nil
+}
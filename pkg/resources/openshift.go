@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	// OpenShift resources, which are handled as unstructured objects not to depend on the OpenShift API
+	deploymentConfigKind     = schema.GroupKind{Group: "apps.openshift.io", Kind: "DeploymentConfig"}
+	deploymentConfigResource = schema.GroupVersionResource{Group: "apps.openshift.io", Version: "v1", Resource: "deploymentconfigs"}
+	routeKind                = schema.GroupKind{Group: "route.openshift.io", Kind: "Route"}
+	routeResource            = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+)
+
+// listOpenShiftResources returns the list of the OpenShift resources in the namespace
+// Empty list is returned if the cluster doesn't serve the resource, like
+// vanilla Kubernetes.
+func listOpenShiftResources(ctx context.Context, client dynamic.Interface, resource schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	list, err := client.Resource(resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return &unstructured.UnstructuredList{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// RouteServiceNames returns the names of the services that the route sends traffic to
+// They are the service in spec.to and the ones in spec.alternateBackends.
+func RouteServiceNames(route *unstructured.Unstructured) []string {
+	names := []string{}
+	backends := []interface{}{}
+	if to, ok, _ := unstructured.NestedMap(route.Object, "spec", "to"); ok {
+		backends = append(backends, to)
+	}
+	if alternates, ok, _ := unstructured.NestedSlice(route.Object, "spec", "alternateBackends"); ok {
+		backends = append(backends, alternates...)
+	}
+
+	for _, b := range backends {
+		backend, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(backend, "kind")
+		name, _, _ := unstructured.NestedString(backend, "name")
+		// kind is Service, which is the only kind allowed, if omitted
+		if (kind == "" || kind == "Service") && name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
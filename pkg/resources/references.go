@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+const (
+	// ingressClassAnnotation is the annotation used to specify the ingress class
+	// before spec.ingressClassName was introduced
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
+	// defaultIngressClassAnnotation is the annotation to mark the default ingress class
+	defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+)
+
+// IngressClassName returns the name of the IngressClass for the ingress
+// It returns empty string if no class is specified and no default class exists.
+func (r *Resources) IngressClassName(ing *networkingv1.Ingress) string {
+	if ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName
+	}
+	if name, ok := ing.GetAnnotations()[ingressClassAnnotation]; ok {
+		return name
+	}
+	for _, class := range r.IngressClasses.Items {
+		if class.GetAnnotations()[defaultIngressClassAnnotation] == "true" {
+			return class.Name
+		}
+	}
+
+	return ""
+}
+
+// PodConfigMapNames returns the names of ConfigMaps referenced by the pod, including its init containers
+// Each name is returned only once, even if it is referenced multiple times.
+func PodConfigMapNames(pod *corev1.Pod) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, vol := range pod.Spec.Volumes {
+		if vol.VolumeSource.ConfigMap != nil {
+			add(vol.VolumeSource.ConfigMap.Name)
+		}
+	}
+	for _, c := range podContainers(pod) {
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add(envFrom.ConfigMapRef.Name)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				add(env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+// PodSecretNames returns the names of Secrets referenced by the pod, including its init containers
+// Each name is returned only once, even if it is referenced multiple times.
+func PodSecretNames(pod *corev1.Pod) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, vol := range pod.Spec.Volumes {
+		if vol.VolumeSource.Secret != nil {
+			add(vol.VolumeSource.Secret.SecretName)
+		}
+	}
+	for _, c := range podContainers(pod) {
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.SecretRef != nil {
+				add(envFrom.SecretRef.Name)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				add(env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	for _, ref := range pod.Spec.ImagePullSecrets {
+		add(ref.Name)
+	}
+
+	return names
+}
+
+// podContainers returns the init containers and the containers of the pod
+func podContainers(pod *corev1.Pod) []corev1.Container {
+	containers := make([]corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	containers = append(containers, pod.Spec.InitContainers...)
+	return append(containers, pod.Spec.Containers...)
+}
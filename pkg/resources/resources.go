@@ -4,112 +4,592 @@
 package resources
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"golang.org/x/sync/errgroup"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	v1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 var (
 	// ResourceTypes represents the set of resource types.
 	// Resouces are grouped by the same level of abstraction.
-	ResourceTypes   = []string{"deploy job", "sts ds rs", "pod", "pvc", "svc", "ing"}
+	ResourceTypes   = []string{"hpa netpol pdb", "deploy dc cronjob", "sts ds rs rc job", "pod", "pvc cm secret sa", "svc rolebinding vs", "ing route role", "ingressclass pv clusterrole vsc", "storageclass node"}
 	normalizedNames = map[string]string{
-		"ns":     "namespace",
-		"svc":    "service",
-		"pvc":    "persistentvolumeclaim",
-		"pod":    "po",
-		"sts":    "statefulset",
-		"ds":     "daemonset",
-		"rs":     "replicaset",
-		"deploy": "deployment",
-		"job":    "job",
-		"ing":    "ingress",
+		"ns":           "namespace",
+		"svc":          "service",
+		"pvc":          "persistentvolumeclaim",
+		"cm":           "configmap",
+		"secret":       "secret",
+		"sa":           "serviceaccount",
+		"pod":          "po",
+		"sts":          "statefulset",
+		"ds":           "daemonset",
+		"rs":           "replicaset",
+		"rc":           "replicationcontroller",
+		"deploy":       "deployment",
+		"job":          "job",
+		"cronjob":      "cronjob",
+		"ing":          "ingress",
+		"hpa":          "horizontalpodautoscaler",
+		"ingressclass": "ingressclass",
+		"netpol":       "networkpolicy",
+		"pdb":          "poddisruptionbudget",
+		"pv":           "persistentvolume",
+		"storageclass": "storageclass",
+		"role":         "role",
+		"rolebinding":  "rolebinding",
+		"clusterrole":  "clusterrole",
+		"dc":           "deploymentconfig",
+		"route":        "route",
+		"node":         "node",
+		"vs":           "volumesnapshot",
+		"vsc":          "volumesnapshotcontent",
 	}
+	// clusterScopedTypes represents the set of resource types that aren't namespaced
+	clusterScopedTypes = []string{"ingressclass", "pv", "storageclass", "clusterrole", "node", "vsc"}
 )
 
 // Resources represents the k8s resources
 type Resources struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 	Namespace string
 
-	Svcs      *corev1.ServiceList
-	Pvcs      *corev1.PersistentVolumeClaimList
-	Pods      *corev1.PodList
-	Stss      *appsv1.StatefulSetList
-	Dss       *appsv1.DaemonSetList
-	Rss       *appsv1.ReplicaSetList
-	Deploys   *appsv1.DeploymentList
-	Jobs      *batchv1.JobList
-	Ingresses *v1beta1.IngressList
+	Svcs            *corev1.ServiceList
+	Pvcs            *corev1.PersistentVolumeClaimList
+	Cms             *corev1.ConfigMapList
+	Secrets         *corev1.SecretList
+	Sas             *corev1.ServiceAccountList
+	Pods            *corev1.PodList
+	Stss            *appsv1.StatefulSetList
+	Dss             *appsv1.DaemonSetList
+	Rss             *appsv1.ReplicaSetList
+	Rcs             *corev1.ReplicationControllerList
+	Deploys         *appsv1.DeploymentList
+	Jobs            *batchv1.JobList
+	CronJobs        *batchv1beta1.CronJobList
+	Ingresses       *networkingv1.IngressList
+	Hpas            *autoscalingv1.HorizontalPodAutoscalerList
+	NetworkPolicies *networkingv1.NetworkPolicyList
+	Pdbs            *policyv1beta1.PodDisruptionBudgetList
+	Roles           *rbacv1.RoleList
+	RoleBindings    *rbacv1.RoleBindingList
+	// Endpoints aren't shown in the graph, but used to find pods behind services
+	Endpoints *corev1.EndpointsList
+	// EndpointSlices aren't shown in the graph, but used to find readiness of pods behind services
+	EndpointSlices *discoveryv1beta1.EndpointSliceList
+	// OpenShift resources, which are empty unless got with FetchOptions.Dynamic or read from manifests
+	DeploymentConfigs *unstructured.UnstructuredList
+	Routes            *unstructured.UnstructuredList
+	// CSI volume snapshots, which are empty unless got with FetchOptions.SnapshotDynamic or read from manifests
+	VolumeSnapshots *unstructured.UnstructuredList
+
+	// Cluster-scoped resources
+	IngressClasses *networkingv1.IngressClassList
+	Pvs            *corev1.PersistentVolumeList
+	StorageClasses *storagev1.StorageClassList
+	ClusterRoles   *rbacv1.ClusterRoleList
+	// Nodes are empty unless got with FetchOptions.Nodes or read from manifests
+	Nodes *corev1.NodeList
+	// VolumeSnapshotContents are empty unless got with FetchOptions.SnapshotDynamic or read from manifests
+	VolumeSnapshotContents *unstructured.UnstructuredList
 }
 
 // NewResources resturns Resources for the namespace
-func NewResources(clientset *kubernetes.Clientset, namespace string) *Resources {
-	var err error
-	res := &Resources{clientset: clientset, Namespace: namespace}
+func NewResources(clientset kubernetes.Interface, namespace string) *Resources {
+	return NewResourcesContext(context.Background(), clientset, namespace)
+}
 
-	// service
-	res.Svcs, err = clientset.CoreV1().Services(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get services in namespace %q: %v\n", namespace, err)
+// NewResourcesContext resturns Resources for the namespace
+// ctx is used for the requests to get k8s resources.
+// Failures to get resources are reported to stderr, and the resources of
+// the types are left empty.
+func NewResourcesContext(ctx context.Context, clientset kubernetes.Interface, namespace string) *Resources {
+	res, _ := FetchResourcesWithOptions(ctx, clientset, namespace, FetchOptions{ContinueOnError: true})
+	return res
+}
+
+// FetchResources returns Resources for the namespace
+// Unlike NewResourcesContext, it returns the first failure to get resources
+// as an error, and cancels the requests in progress.
+func FetchResources(ctx context.Context, clientset kubernetes.Interface, namespace string) (*Resources, error) {
+	return FetchResourcesWithOptions(ctx, clientset, namespace, FetchOptions{})
+}
+
+// FetchOptions represents the options to get k8s resources from the cluster
+// The zero value gets the resources of each type only once, and returns the
+// first failure like FetchResources.
+type FetchOptions struct {
+	// ContinueOnError reports failures to get resources to stderr and leaves
+	// the resources of the types empty, like NewResourcesContext, instead of
+	// returning the first failure.
+	ContinueOnError bool
+	// Retries is the number of retries to get the resources of each type on
+	// transient errors, like timeouts and too many requests. Permanent errors,
+	// like forbidden and not found, aren't retried.
+	Retries int
+	// RetryInterval is the time to wait before the first retry, which is
+	// doubled for each retry. One second is used if zero.
+	RetryInterval time.Duration
+	// Dynamic is the client to get OpenShift resources, DeploymentConfigs and
+	// Routes. They are left empty if nil, or if the cluster doesn't serve
+	// them, like vanilla Kubernetes.
+	Dynamic dynamic.Interface
+	// SnapshotDynamic is the client to get CSI volume snapshots,
+	// VolumeSnapshots and VolumeSnapshotContents. They are left empty if nil,
+	// or if the cluster doesn't serve them, like the clusters without the CRDs
+	// of CSI volume snapshots. Only the volumesnapshotcontents bound to the
+	// volumesnapshots in the namespace are kept.
+	SnapshotDynamic dynamic.Interface
+	// Nodes gets the nodes that pods in the namespace run on. They are left
+	// empty if false, as nodes aren't namespaced and getting them needs the
+	// permission to list the nodes of the cluster.
+	Nodes bool
+	// Progress is written the messages on getting the resources of each type,
+	// like "fetching services in namespace \"default\"...".
+	// Nothing is written if nil.
+	Progress io.Writer
+}
+
+// FetchResourcesWithOptions returns Resources for the namespace got with opts
+// All resource types are got concurrently. Unless ContinueOnError is set in
+// opts, the first failure is returned and the other requests are canceled.
+// Cluster-scoped resources that aren't permitted to list, like for users
+// bound to roles only in the namespace, are left empty with the warnings to
+// stderr instead of the failures.
+func FetchResourcesWithOptions(ctx context.Context, clientset kubernetes.Interface, namespace string, opts FetchOptions) (*Resources, error) {
+	res := newEmptyResources(namespace)
+	res.clientset = clientset
+
+	eg, ctx := errgroup.WithContext(ctx)
+	// progressMu serializes the messages written to opts.Progress by goroutines
+	var progressMu sync.Mutex
+	// fetch gets the resources described as desc with f in a goroutine
+	fetch := func(desc string, f func(ctx context.Context) error) {
+		eg.Go(func() error {
+			if opts.Progress != nil {
+				progressMu.Lock()
+				fmt.Fprintf(opts.Progress, "fetching %s...\n", desc)
+				progressMu.Unlock()
+			}
+			if err := retryTransient(ctx, opts, f); err != nil {
+				if !opts.ContinueOnError {
+					return fmt.Errorf("failed to get %s: %v", desc, err)
+				}
+				fmt.Fprintf(os.Stderr, "Failed to get %s: %v\n", desc, err)
+			}
+			return nil
+		})
 	}
+	// fetchClusterScoped gets the cluster-scoped resources described as desc with f like fetch
+	// Forbidden lists are reported, but aren't failures, as the resources in
+	// the namespace can be shown without them.
+	fetchClusterScoped := func(desc string, f func(ctx context.Context) error) {
+		fetch(desc, func(ctx context.Context) error {
+			err := f(ctx)
+			if apierrors.IsForbidden(err) {
+				fmt.Fprintf(os.Stderr, "Failed to get %s, which are left empty: %v\n", desc, err)
+				return nil
+			}
+			return err
+		})
+	}
+
+	// service
+	fetch(fmt.Sprintf("services in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Svcs = list
+		return nil
+	})
 
 	// persistentvolumeclaim
-	res.Pvcs, err = clientset.CoreV1().PersistentVolumeClaims(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get persistentVolumeClaims in namespace %q: %v\n", namespace, err)
-	}
+	fetch(fmt.Sprintf("persistentVolumeClaims in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Pvcs = list
+		return nil
+	})
+
+	// configmap
+	fetch(fmt.Sprintf("configmaps in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Cms = list
+		return nil
+	})
+
+	// secret
+	fetch(fmt.Sprintf("secrets in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Secrets = list
+		return nil
+	})
+
+	// serviceaccount
+	fetch(fmt.Sprintf("serviceaccounts in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Sas = list
+		return nil
+	})
 
 	// pod
-	res.Pods, err = clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get pods in namespace %q: %v\n", namespace, err)
-	}
+	fetch(fmt.Sprintf("pods in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Pods = list
+		return nil
+	})
 
 	// statefulset
-	res.Stss, err = clientset.AppsV1().StatefulSets(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get statefulsets in namespace %q: %v\n", namespace, err)
-	}
+	fetch(fmt.Sprintf("statefulsets in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Stss = list
+		return nil
+	})
 
 	// daemonset
-	res.Dss, err = clientset.AppsV1().DaemonSets(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get daemonsets in namespace %q: %v\n", namespace, err)
-	}
+	fetch(fmt.Sprintf("daemonsets in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Dss = list
+		return nil
+	})
 
 	// replicaset
-	res.Rss, err = clientset.AppsV1().ReplicaSets(namespace).List(metav1.ListOptions{"FieldSelector":{"Status": "Running"}})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get replicasets in namespace %q: %v\n", namespace, err)
-	}
+	fetch(fmt.Sprintf("replicasets in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Rss = list
+		return nil
+	})
+
+	// replicationcontroller
+	fetch(fmt.Sprintf("replicationcontrollers in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().ReplicationControllers(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Rcs = list
+		return nil
+	})
 
 	// deployment
-	res.Deploys, err = clientset.AppsV1().Deployments(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get deployments in namespace %q: %v\n", namespace, err)
-	}
+	fetch(fmt.Sprintf("deployments in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Deploys = list
+		return nil
+	})
 
 	// job
-	res.Jobs, err = clientset.BatchV1().Jobs(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get jobs in namespace %q: %v\n", namespace, err)
-	}
+	fetch(fmt.Sprintf("jobs in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Jobs = list
+		return nil
+	})
+
+	// cronjob
+	fetch(fmt.Sprintf("cronjobs in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.BatchV1beta1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.CronJobs = list
+		return nil
+	})
 
 	// ingress
-	res.Ingresses, err = clientset.ExtensionsV1beta1().Ingresses(namespace).List(metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get ingresses in namespace %q: %v\n", namespace, err)
+	fetch(fmt.Sprintf("ingresses in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			// Fall back to extensions/v1beta1 for clusters older than 1.19
+			var legacy *v1beta1.IngressList
+			legacy, err = clientset.ExtensionsV1beta1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+			if err == nil {
+				list = convertIngressList(legacy)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		res.Ingresses = list
+		return nil
+	})
+
+	// horizontalpodautoscaler
+	fetch(fmt.Sprintf("horizontalpodautoscalers in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Hpas = list
+		return nil
+	})
+
+	// networkpolicy
+	fetch(fmt.Sprintf("networkpolicies in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.NetworkPolicies = list
+		return nil
+	})
+
+	// poddisruptionbudget
+	fetch(fmt.Sprintf("poddisruptionbudgets in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.PolicyV1beta1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Pdbs = list
+		return nil
+	})
+
+	// role
+	fetch(fmt.Sprintf("roles in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.RbacV1().Roles(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Roles = list
+		return nil
+	})
+
+	// rolebinding
+	fetch(fmt.Sprintf("rolebindings in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.RoleBindings = list
+		return nil
+	})
+
+	// endpoints
+	fetch(fmt.Sprintf("endpoints in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.CoreV1().Endpoints(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Endpoints = list
+		return nil
+	})
+
+	// endpointslice
+	fetch(fmt.Sprintf("endpointslices in namespace %q", namespace), func(ctx context.Context) error {
+		list, err := clientset.DiscoveryV1beta1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.EndpointSlices = list
+		return nil
+	})
+
+	// deploymentconfig and route of OpenShift
+	if opts.Dynamic != nil {
+		fetch(fmt.Sprintf("deploymentconfigs in namespace %q", namespace), func(ctx context.Context) error {
+			list, err := listOpenShiftResources(ctx, opts.Dynamic, deploymentConfigResource, namespace)
+			if err != nil {
+				return err
+			}
+			res.DeploymentConfigs = list
+			return nil
+		})
+
+		fetch(fmt.Sprintf("routes in namespace %q", namespace), func(ctx context.Context) error {
+			list, err := listOpenShiftResources(ctx, opts.Dynamic, routeResource, namespace)
+			if err != nil {
+				return err
+			}
+			res.Routes = list
+			return nil
+		})
 	}
 
-	return res
+	// volumesnapshot and volumesnapshotcontent of CSI
+	if opts.SnapshotDynamic != nil {
+		fetch(fmt.Sprintf("volumesnapshots in namespace %q", namespace), func(ctx context.Context) error {
+			list, err := listVolumeSnapshotResources(ctx, opts.SnapshotDynamic, "volumesnapshots", namespace)
+			if err != nil {
+				return err
+			}
+			res.VolumeSnapshots = list
+			return nil
+		})
+
+		fetch("volumesnapshotcontents", func(ctx context.Context) error {
+			list, err := listVolumeSnapshotResources(ctx, opts.SnapshotDynamic, "volumesnapshotcontents", "")
+			if err != nil {
+				return err
+			}
+			res.VolumeSnapshotContents = list
+			return nil
+		})
+	}
+
+	// ingressclass
+	fetchClusterScoped("ingressclasses", func(ctx context.Context) error {
+		list, err := clientset.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.IngressClasses = list
+		return nil
+	})
+
+	// persistentvolume
+	// Only the ones bound to the claims in the namespace are kept after all the fetches.
+	fetchClusterScoped("persistentvolumes", func(ctx context.Context) error {
+		list, err := clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.Pvs = list
+		return nil
+	})
+
+	// storageclass
+	fetchClusterScoped("storageclasses", func(ctx context.Context) error {
+		list, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.StorageClasses = list
+		return nil
+	})
+
+	// clusterrole
+	// Only the ones referenced by rolebindings in the namespace are kept after all the fetches.
+	fetchClusterScoped("clusterroles", func(ctx context.Context) error {
+		list, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		res.ClusterRoles = list
+		return nil
+	})
+
+	// node
+	// Only the ones that pods in the namespace run on are kept after all the fetches.
+	if opts.Nodes {
+		fetch("nodes", func(ctx context.Context) error {
+			list, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			res.Nodes = list
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	res.dropUnclaimedPersistentVolumes()
+	res.dropUnreferencedClusterRoles()
+	res.dropUnusedNodes()
+	res.dropUnboundVolumeSnapshotContents()
+
+	return res, nil
+}
+
+// dropUnclaimedPersistentVolumes drops persistentvolumes that aren't bound to the claims in the namespace of r
+// Persistentvolumes aren't namespaced, and most of them may be used in other namespaces.
+func (r *Resources) dropUnclaimedPersistentVolumes() {
+	pvs := r.Pvs.Items[:0]
+	for _, pv := range r.Pvs.Items {
+		if pv.Spec.ClaimRef != nil && pv.Spec.ClaimRef.Namespace == r.Namespace {
+			pvs = append(pvs, pv)
+		}
+	}
+	r.Pvs.Items = pvs
+}
+
+// dropUnreferencedClusterRoles drops clusterroles that no rolebinding in r refers to
+// Clusterroles aren't namespaced, and most of them aren't related to the namespace.
+func (r *Resources) dropUnreferencedClusterRoles() {
+	referenced := map[string]bool{}
+	for _, rb := range r.RoleBindings.Items {
+		if rb.RoleRef.Kind == "ClusterRole" {
+			referenced[rb.RoleRef.Name] = true
+		}
+	}
+
+	clusterRoles := r.ClusterRoles.Items[:0]
+	for _, cr := range r.ClusterRoles.Items {
+		if referenced[cr.Name] {
+			clusterRoles = append(clusterRoles, cr)
+		}
+	}
+	r.ClusterRoles.Items = clusterRoles
+}
+
+// dropUnusedNodes drops nodes that no pod in r runs on
+// Nodes aren't namespaced, and most of them may not run pods in the namespace.
+func (r *Resources) dropUnusedNodes() {
+	used := map[string]bool{}
+	for _, pod := range r.Pods.Items {
+		if pod.Spec.NodeName != "" {
+			used[pod.Spec.NodeName] = true
+		}
+	}
+
+	nodes := r.Nodes.Items[:0]
+	for _, node := range r.Nodes.Items {
+		if used[node.Name] {
+			nodes = append(nodes, node)
+		}
+	}
+	r.Nodes.Items = nodes
 }
 
 // GetResourceNames returns the resource names of the kind
@@ -125,6 +605,18 @@ func (r *Resources) GetResourceNames(kind string) []string {
 		for _, n := range r.Pvcs.Items {
 			names = append(names, n.Name)
 		}
+	case "cm":
+		for _, n := range r.Cms.Items {
+			names = append(names, n.Name)
+		}
+	case "secret":
+		for _, n := range r.Secrets.Items {
+			names = append(names, n.Name)
+		}
+	case "sa":
+		for _, n := range r.Sas.Items {
+			names = append(names, n.Name)
+		}
 	case "pod":
 		for _, n := range r.Pods.Items {
 			names = append(names, n.Name)
@@ -141,6 +633,10 @@ func (r *Resources) GetResourceNames(kind string) []string {
 		for _, n := range r.Rss.Items {
 			names = append(names, n.Name)
 		}
+	case "rc":
+		for _, n := range r.Rcs.Items {
+			names = append(names, n.Name)
+		}
 	case "deploy":
 		for _, n := range r.Deploys.Items {
 			names = append(names, n.Name)
@@ -149,15 +645,252 @@ func (r *Resources) GetResourceNames(kind string) []string {
 		for _, n := range r.Jobs.Items {
 			names = append(names, n.Name)
 		}
+	case "cronjob":
+		for _, n := range r.CronJobs.Items {
+			names = append(names, n.Name)
+		}
 	case "ing":
 		for _, n := range r.Ingresses.Items {
 			names = append(names, n.Name)
 		}
+	case "hpa":
+		for _, n := range r.Hpas.Items {
+			names = append(names, n.Name)
+		}
+	case "ingressclass":
+		for _, n := range r.IngressClasses.Items {
+			names = append(names, n.Name)
+		}
+	case "netpol":
+		for _, n := range r.NetworkPolicies.Items {
+			names = append(names, n.Name)
+		}
+	case "pdb":
+		for _, n := range r.Pdbs.Items {
+			names = append(names, n.Name)
+		}
+	case "pv":
+		for _, n := range r.Pvs.Items {
+			names = append(names, n.Name)
+		}
+	case "storageclass":
+		for _, n := range r.StorageClasses.Items {
+			names = append(names, n.Name)
+		}
+	case "role":
+		for _, n := range r.Roles.Items {
+			names = append(names, n.Name)
+		}
+	case "rolebinding":
+		for _, n := range r.RoleBindings.Items {
+			names = append(names, n.Name)
+		}
+	case "clusterrole":
+		for _, n := range r.ClusterRoles.Items {
+			names = append(names, n.Name)
+		}
+	case "dc":
+		for _, n := range r.DeploymentConfigs.Items {
+			names = append(names, n.GetName())
+		}
+	case "route":
+		for _, n := range r.Routes.Items {
+			names = append(names, n.GetName())
+		}
+	case "node":
+		for _, n := range r.Nodes.Items {
+			names = append(names, n.Name)
+		}
+	case "vs":
+		for _, n := range r.VolumeSnapshots.Items {
+			names = append(names, n.GetName())
+		}
+	case "vsc":
+		for _, n := range r.VolumeSnapshotContents.Items {
+			names = append(names, n.GetName())
+		}
 	}
 
 	return names
 }
 
+// GetResource returns the k8s resource with the kind and the name
+// It returns nil if Resources doesn't have the resource.
+func (r *Resources) GetResource(kind, name string) runtime.Object {
+	switch kind {
+	case "svc":
+		for i := range r.Svcs.Items {
+			if r.Svcs.Items[i].Name == name {
+				return &r.Svcs.Items[i]
+			}
+		}
+	case "pvc":
+		for i := range r.Pvcs.Items {
+			if r.Pvcs.Items[i].Name == name {
+				return &r.Pvcs.Items[i]
+			}
+		}
+	case "cm":
+		for i := range r.Cms.Items {
+			if r.Cms.Items[i].Name == name {
+				return &r.Cms.Items[i]
+			}
+		}
+	case "secret":
+		for i := range r.Secrets.Items {
+			if r.Secrets.Items[i].Name == name {
+				return &r.Secrets.Items[i]
+			}
+		}
+	case "sa":
+		for i := range r.Sas.Items {
+			if r.Sas.Items[i].Name == name {
+				return &r.Sas.Items[i]
+			}
+		}
+	case "pod":
+		for i := range r.Pods.Items {
+			if r.Pods.Items[i].Name == name {
+				return &r.Pods.Items[i]
+			}
+		}
+	case "sts":
+		for i := range r.Stss.Items {
+			if r.Stss.Items[i].Name == name {
+				return &r.Stss.Items[i]
+			}
+		}
+	case "ds":
+		for i := range r.Dss.Items {
+			if r.Dss.Items[i].Name == name {
+				return &r.Dss.Items[i]
+			}
+		}
+	case "rs":
+		for i := range r.Rss.Items {
+			if r.Rss.Items[i].Name == name {
+				return &r.Rss.Items[i]
+			}
+		}
+	case "rc":
+		for i := range r.Rcs.Items {
+			if r.Rcs.Items[i].Name == name {
+				return &r.Rcs.Items[i]
+			}
+		}
+	case "deploy":
+		for i := range r.Deploys.Items {
+			if r.Deploys.Items[i].Name == name {
+				return &r.Deploys.Items[i]
+			}
+		}
+	case "job":
+		for i := range r.Jobs.Items {
+			if r.Jobs.Items[i].Name == name {
+				return &r.Jobs.Items[i]
+			}
+		}
+	case "cronjob":
+		for i := range r.CronJobs.Items {
+			if r.CronJobs.Items[i].Name == name {
+				return &r.CronJobs.Items[i]
+			}
+		}
+	case "ing":
+		for i := range r.Ingresses.Items {
+			if r.Ingresses.Items[i].Name == name {
+				return &r.Ingresses.Items[i]
+			}
+		}
+	case "hpa":
+		for i := range r.Hpas.Items {
+			if r.Hpas.Items[i].Name == name {
+				return &r.Hpas.Items[i]
+			}
+		}
+	case "ingressclass":
+		for i := range r.IngressClasses.Items {
+			if r.IngressClasses.Items[i].Name == name {
+				return &r.IngressClasses.Items[i]
+			}
+		}
+	case "netpol":
+		for i := range r.NetworkPolicies.Items {
+			if r.NetworkPolicies.Items[i].Name == name {
+				return &r.NetworkPolicies.Items[i]
+			}
+		}
+	case "pdb":
+		for i := range r.Pdbs.Items {
+			if r.Pdbs.Items[i].Name == name {
+				return &r.Pdbs.Items[i]
+			}
+		}
+	case "pv":
+		for i := range r.Pvs.Items {
+			if r.Pvs.Items[i].Name == name {
+				return &r.Pvs.Items[i]
+			}
+		}
+	case "storageclass":
+		for i := range r.StorageClasses.Items {
+			if r.StorageClasses.Items[i].Name == name {
+				return &r.StorageClasses.Items[i]
+			}
+		}
+	case "role":
+		for i := range r.Roles.Items {
+			if r.Roles.Items[i].Name == name {
+				return &r.Roles.Items[i]
+			}
+		}
+	case "rolebinding":
+		for i := range r.RoleBindings.Items {
+			if r.RoleBindings.Items[i].Name == name {
+				return &r.RoleBindings.Items[i]
+			}
+		}
+	case "clusterrole":
+		for i := range r.ClusterRoles.Items {
+			if r.ClusterRoles.Items[i].Name == name {
+				return &r.ClusterRoles.Items[i]
+			}
+		}
+	case "dc":
+		for i := range r.DeploymentConfigs.Items {
+			if r.DeploymentConfigs.Items[i].GetName() == name {
+				return &r.DeploymentConfigs.Items[i]
+			}
+		}
+	case "route":
+		for i := range r.Routes.Items {
+			if r.Routes.Items[i].GetName() == name {
+				return &r.Routes.Items[i]
+			}
+		}
+	case "node":
+		for i := range r.Nodes.Items {
+			if r.Nodes.Items[i].Name == name {
+				return &r.Nodes.Items[i]
+			}
+		}
+	case "vs":
+		for i := range r.VolumeSnapshots.Items {
+			if r.VolumeSnapshots.Items[i].GetName() == name {
+				return &r.VolumeSnapshots.Items[i]
+			}
+		}
+	case "vsc":
+		for i := range r.VolumeSnapshotContents.Items {
+			if r.VolumeSnapshotContents.Items[i].GetName() == name {
+				return &r.VolumeSnapshotContents.Items[i]
+			}
+		}
+	}
+
+	return nil
+}
+
 // HasResource check if Resources has k8s resource with the kind and the name
 func (r *Resources) HasResource(kind, name string) bool {
 	for _, resName := range r.GetResourceNames(kind) {
@@ -168,6 +901,16 @@ func (r *Resources) HasResource(kind, name string) bool {
 	return false
 }
 
+// IsClusterScoped checks if the resource type isn't namespaced
+func IsClusterScoped(kind string) bool {
+	for _, t := range clusterScopedTypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
 // NormalizeResource resturns normalized name of the resource.
 // It returns error if it fails to normalize the resource name.
 // key of normalizedNames map is used as the normalized name.
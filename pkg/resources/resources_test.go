@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newFakeClientset returns the fake clientset serving a deployment and a service in namespace "default",
+// and a service in namespace "other"
+func newFakeClientset() *fake.Clientset {
+	return fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "db"}},
+	)
+}
+
+// forbidList makes the lists of resource by clientset fail as forbidden
+func forbidList(clientset *fake.Clientset, resource string) {
+	clientset.PrependReactor("list", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: resource}, "", nil)
+	})
+}
+
+// captureStderr returns what f writes to os.Stderr
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	f()
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestFetchResourcesWithOptions(t *testing.T) {
+	res, err := FetchResourcesWithOptions(context.Background(), newFakeClientset(), "default", FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := res.GetResourceNames("deploy"); len(got) != 1 || got[0] != "web" {
+		t.Errorf("deployments = %v, want [web]", got)
+	}
+	if got := res.GetResourceNames("svc"); len(got) != 1 || got[0] != "web" {
+		t.Errorf("services = %v, want [web]", got)
+	}
+	if got := res.GetResourceNames("pod"); len(got) != 0 {
+		t.Errorf("pods = %v, want none", got)
+	}
+}
+
+func TestFetchResourcesWithOptionsError(t *testing.T) {
+	clientset := newFakeClientset()
+	forbidList(clientset, "services")
+
+	res, err := FetchResourcesWithOptions(context.Background(), clientset, "default", FetchOptions{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "failed to get services") {
+		t.Errorf("error = %q, want failure to get services", err)
+	}
+	if res != nil {
+		t.Errorf("resources = %v, want nil", res)
+	}
+}
+
+func TestFetchResourcesWithOptionsContinueOnError(t *testing.T) {
+	clientset := newFakeClientset()
+	forbidList(clientset, "services")
+
+	var res *Resources
+	var err error
+	stderr := captureStderr(t, func() {
+		res, err = FetchResourcesWithOptions(context.Background(), clientset, "default", FetchOptions{ContinueOnError: true})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(stderr, "Failed to get services") {
+		t.Errorf("stderr = %q, want warning on services", stderr)
+	}
+	if got := res.GetResourceNames("svc"); len(got) != 0 {
+		t.Errorf("services = %v, want none", got)
+	}
+	if got := res.GetResourceNames("deploy"); len(got) != 1 || got[0] != "web" {
+		t.Errorf("deployments = %v, want [web]", got)
+	}
+}
+
+func TestFetchResourcesWithOptionsForbiddenClusterScoped(t *testing.T) {
+	for _, resource := range []string{"ingressclasses", "persistentvolumes", "storageclasses", "clusterroles"} {
+		t.Run(resource, func(t *testing.T) {
+			clientset := newFakeClientset()
+			forbidList(clientset, resource)
+
+			var res *Resources
+			var err error
+			stderr := captureStderr(t, func() {
+				res, err = FetchResourcesWithOptions(context.Background(), clientset, "default", FetchOptions{})
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if want := "Failed to get " + resource + ", which are left empty"; !strings.Contains(stderr, want) {
+				t.Errorf("stderr = %q, want %q in it", stderr, want)
+			}
+			if got := res.GetResourceNames("deploy"); len(got) != 1 || got[0] != "web" {
+				t.Errorf("deployments = %v, want [web]", got)
+			}
+		})
+	}
+}
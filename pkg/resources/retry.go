@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+const (
+	// defaultRetryInterval is the time to wait before the first retry to get resources
+	defaultRetryInterval = time.Second
+)
+
+// retryTransient calls f until it succeeds, fails with a permanent error,
+// or fails Retries+1 times in opts
+// The interval between calls starts from RetryInterval in opts, and is
+// doubled for each retry. It returns the last error of f, if ctx is done
+// while waiting for the retry.
+func retryTransient(ctx context.Context, opts FetchOptions, f func(ctx context.Context) error) error {
+	interval := opts.RetryInterval
+	if interval == 0 {
+		interval = defaultRetryInterval
+	}
+
+	err := f(ctx)
+	for i := 0; i < opts.Retries && err != nil && isTransient(err); i++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+		interval *= 2
+		err = f(ctx)
+	}
+
+	return err
+}
+
+// isTransient checks if err is the failure of the request that may succeed on retries
+// ex) timeouts, too many requests, and connections closed by the server
+func isTransient(err error) bool {
+	return apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) ||
+		utilnet.IsTimeout(err) || utilnet.IsProbableEOF(err) || utilnet.IsConnectionReset(err)
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	errTransient = apierrors.NewTooManyRequests("slow down", 0)
+	errPermanent = apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", nil)
+)
+
+func TestRetryTransient(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", retries: 3, wantCalls: 1},
+		{name: "no retry", retries: 0, errs: []error{errTransient}, wantCalls: 1, wantErr: errTransient},
+		{name: "success on retry", retries: 3, errs: []error{errTransient, errTransient}, wantCalls: 3},
+		{name: "retries exhausted", retries: 3, errs: []error{errTransient, errTransient, errTransient, errTransient, errTransient}, wantCalls: 4, wantErr: errTransient},
+		{name: "permanent error", retries: 3, errs: []error{errPermanent}, wantCalls: 1, wantErr: errPermanent},
+		{name: "permanent error on retry", retries: 3, errs: []error{errTransient, errPermanent}, wantCalls: 2, wantErr: errPermanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retryTransient(context.Background(), FetchOptions{Retries: tt.retries, RetryInterval: time.Millisecond}, func(ctx context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryTransientInterval(t *testing.T) {
+	times := []time.Time{}
+	retryTransient(context.Background(), FetchOptions{Retries: 3, RetryInterval: time.Millisecond}, func(ctx context.Context) error {
+		times = append(times, time.Now())
+		return errTransient
+	})
+	if len(times) != 4 {
+		t.Fatalf("calls = %d, want 4", len(times))
+	}
+
+	interval := time.Millisecond
+	for i := 1; i < len(times); i++ {
+		if got := times[i].Sub(times[i-1]); got < interval {
+			t.Errorf("interval before retry %d = %v, want %v or longer", i, got, interval)
+		}
+		interval *= 2
+	}
+}
+
+func TestRetryTransientCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- retryTransient(ctx, FetchOptions{Retries: 3, RetryInterval: time.Hour}, func(ctx context.Context) error {
+			calls++
+			cancel()
+			return errTransient
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != errTransient {
+			t.Errorf("error = %v, want %v", err, errTransient)
+		}
+		if calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("retryTransient didn't return on cancel")
+	}
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// snapshotGroup is the API group of CSI volume snapshots, which are served only if their CRDs are installed
+	snapshotGroup = "snapshot.storage.k8s.io"
+)
+
+var (
+	// CSI volume snapshots, which are handled as unstructured objects not to depend on the external snapshotter
+	volumeSnapshotKind        = schema.GroupKind{Group: snapshotGroup, Kind: "VolumeSnapshot"}
+	volumeSnapshotContentKind = schema.GroupKind{Group: snapshotGroup, Kind: "VolumeSnapshotContent"}
+	// snapshotVersions is the list of the versions of the API to try in order, as v1 is served only by the newer snapshotters
+	snapshotVersions = []string{"v1", "v1beta1"}
+)
+
+// listVolumeSnapshotResources returns the list of the resource of CSI volume snapshots in the namespace
+// The resource is like "volumesnapshots", and all namespaces are listed if
+// namespace is empty, for cluster-scoped volumesnapshotcontents. Empty list is
+// returned if the cluster serves none of snapshotVersions, like the clusters
+// without the CRDs of CSI volume snapshots.
+func listVolumeSnapshotResources(ctx context.Context, client dynamic.Interface, resource, namespace string) (*unstructured.UnstructuredList, error) {
+	for _, version := range snapshotVersions {
+		gvr := schema.GroupVersionResource{Group: snapshotGroup, Version: version, Resource: resource}
+		list, err := client.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+
+	return &unstructured.UnstructuredList{}, nil
+}
+
+// VolumeSnapshotSource returns the name of the pvc that the volumesnapshot is taken from
+// It is spec.source.persistentVolumeClaimName, which is empty for the
+// volumesnapshots of pre-provisioned contents.
+func VolumeSnapshotSource(vs *unstructured.Unstructured) string {
+	name, _, _ := unstructured.NestedString(vs.Object, "spec", "source", "persistentVolumeClaimName")
+	return name
+}
+
+// VolumeSnapshotContentName returns the name of the volumesnapshotcontent that the volumesnapshot is bound to
+// It is status.boundVolumeSnapshotContentName, or
+// spec.source.volumeSnapshotContentName for pre-provisioned contents if the
+// volumesnapshot isn't bound yet. Empty string is returned if neither is set.
+func VolumeSnapshotContentName(vs *unstructured.Unstructured) string {
+	if name, _, _ := unstructured.NestedString(vs.Object, "status", "boundVolumeSnapshotContentName"); name != "" {
+		return name
+	}
+	name, _, _ := unstructured.NestedString(vs.Object, "spec", "source", "volumeSnapshotContentName")
+	return name
+}
+
+// dropUnboundVolumeSnapshotContents drops volumesnapshotcontents that aren't bound to the volumesnapshots in the namespace of r
+// Volumesnapshotcontents aren't namespaced, and most of them may be bound in other namespaces.
+func (r *Resources) dropUnboundVolumeSnapshotContents() {
+	contents := r.VolumeSnapshotContents.Items[:0]
+	for _, o := range r.VolumeSnapshotContents.Items {
+		if namespace, _, _ := unstructured.NestedString(o.Object, "spec", "volumeSnapshotRef", "namespace"); namespace == r.Namespace {
+			contents = append(contents, o)
+		}
+	}
+	r.VolumeSnapshotContents.Items = contents
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DanglingReferenceError represents a reference from a k8s resource to the one that isn't found
+type DanglingReferenceError struct {
+	// Kind and Name are the resource type and the name of the resource that has the reference
+	Kind string
+	Name string
+	// RefKind and RefName are the resource type and the name of the referenced resource
+	RefKind string
+	RefName string
+	// Reference describes how the resource is referenced, like "a volume"
+	Reference string
+}
+
+// Error returns the message of the error
+// ex) pvc my-pvc not found as a volume for pod my-pod
+func (e *DanglingReferenceError) Error() string {
+	return fmt.Sprintf("%s %s not found as %s for %s %s", e.RefKind, e.RefName, e.Reference, e.Kind, e.Name)
+}
+
+// Validate returns the errors for the references to k8s resources that aren't found in r
+// The references checked are the same as the ones shown as edges in the graph.
+// It returns empty list if no dangling reference is found.
+func (r *Resources) Validate() []error {
+	errs := []error{}
+	check := func(kind, name, refKind, refName, reference string) {
+		if !r.HasResource(refKind, refName) {
+			errs = append(errs, &DanglingReferenceError{Kind: kind, Name: name, RefKind: refKind, RefName: refName, Reference: reference})
+		}
+	}
+	checkOwners := func(kind, name string, refs []metav1.OwnerReference) {
+		for _, ref := range refs {
+			ownerKind, err := NormalizeResource(ref.Kind)
+			if err != nil {
+				// Skip resource that isn't available for this tool, like CRD
+				continue
+			}
+			check(kind, name, ownerKind, ref.Name, "an owner reference")
+		}
+	}
+
+	for _, pod := range r.Pods.Items {
+		checkOwners("pod", pod.Name, pod.GetOwnerReferences())
+		for _, vol := range pod.Spec.Volumes {
+			if vol.VolumeSource.PersistentVolumeClaim != nil {
+				check("pod", pod.Name, "pvc", vol.VolumeSource.PersistentVolumeClaim.ClaimName, "a volume")
+			}
+		}
+		for _, name := range PodConfigMapNames(&pod) {
+			check("pod", pod.Name, "cm", name, "a reference")
+		}
+		for _, name := range PodSecretNames(&pod) {
+			check("pod", pod.Name, "secret", name, "a reference")
+		}
+		// Only the service account specified explicitly is checked,
+		// as the default one is usually not in manifests
+		if name := pod.Spec.ServiceAccountName; name != "" {
+			check("pod", pod.Name, "sa", name, "a service account")
+		}
+	}
+	for _, rs := range r.Rss.Items {
+		checkOwners("rs", rs.Name, rs.GetOwnerReferences())
+	}
+	for _, rc := range r.Rcs.Items {
+		checkOwners("rc", rc.Name, rc.GetOwnerReferences())
+	}
+	for _, job := range r.Jobs.Items {
+		checkOwners("job", job.Name, job.GetOwnerReferences())
+	}
+
+	for _, pvc := range r.Pvcs.Items {
+		if pvc.Spec.VolumeName != "" {
+			check("pvc", pvc.Name, "pv", pvc.Spec.VolumeName, "a volume")
+		}
+		if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+			check("pvc", pvc.Name, "storageclass", *pvc.Spec.StorageClassName, "a storage class")
+		}
+	}
+
+	for i := range r.VolumeSnapshots.Items {
+		vs := &r.VolumeSnapshots.Items[i]
+		if name := VolumeSnapshotSource(vs); name != "" {
+			check("vs", vs.GetName(), "pvc", name, "a source")
+		}
+		if name := VolumeSnapshotContentName(vs); name != "" {
+			check("vs", vs.GetName(), "vsc", name, "a content")
+		}
+	}
+
+	for _, ing := range r.Ingresses.Items {
+		if ing.Spec.DefaultBackend != nil && ing.Spec.DefaultBackend.Service != nil {
+			check("ing", ing.Name, "svc", ing.Spec.DefaultBackend.Service.Name, "a backend")
+		}
+		for _, rule := range ing.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service != nil {
+					check("ing", ing.Name, "svc", path.Backend.Service.Name, "a backend")
+				}
+			}
+		}
+		if name := r.IngressClassName(&ing); name != "" {
+			check("ing", ing.Name, "ingressclass", name, "an ingress class")
+		}
+	}
+
+	for i := range r.Routes.Items {
+		route := &r.Routes.Items[i]
+		for _, name := range RouteServiceNames(route) {
+			check("route", route.GetName(), "svc", name, "a backend")
+		}
+	}
+
+	for _, rb := range r.RoleBindings.Items {
+		if roleKind, err := NormalizeResource(rb.RoleRef.Kind); err == nil {
+			check("rolebinding", rb.Name, roleKind, rb.RoleRef.Name, "a role")
+		}
+		for _, subject := range rb.Subjects {
+			if subject.Kind == rbacv1.ServiceAccountKind && (subject.Namespace == "" || subject.Namespace == r.Namespace) {
+				check("rolebinding", rb.Name, "sa", subject.Name, "a subject")
+			}
+		}
+	}
+
+	for _, hpa := range r.Hpas.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		targetKind, err := NormalizeResource(ref.Kind)
+		if err != nil {
+			// Skip resource that isn't available for this tool, like CRD
+			continue
+		}
+		check("hpa", hpa.Name, targetKind, ref.Name, "a scale target")
+	}
+
+	return errs
+}
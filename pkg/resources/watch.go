@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// watchDebounce is the time to wait for no more changes, before calling back with the resources
+	// Changes of resources usually come in bursts, like a rollout of a deployment.
+	watchDebounce = time.Second
+	// watchMaxWait is the longest time to wait for no more changes
+	// Resources may keep changing, like the statuses of pods in a large namespace.
+	watchMaxWait = 10 * time.Second
+)
+
+// Watch watches the resources in the namespace, and calls onUpdate with them on changes
+// See WatchWithOptions for details.
+func Watch(ctx context.Context, clientset kubernetes.Interface, namespace string, onUpdate func(*Resources)) error {
+	return WatchWithOptions(ctx, clientset, namespace, FetchOptions{}, onUpdate)
+}
+
+// WatchWithOptions watches the resources in the namespace got with opts, and calls onUpdate with them on changes
+// onUpdate is called with all the resources once they are got first, and
+// then each time resources haven't been changed for a second after changes,
+// not to be called for each change in bursts. While resources keep
+// changing, it is still called every ten seconds. It is called in the
+// goroutine of WatchWithOptions, so the next call waits for the previous one.
+// Resources passed to onUpdate are copies, which can be modified.
+//
+// WatchWithOptions blocks until ctx is done, and the informers to watch are
+// stopped then. It returns an error only if ctx is done before the resources
+// of all types are got first. The types that fail to be got first, like the
+// ones not permitted to list, are reported to stderr and left empty, not to
+// block onUpdate. Their informers keep retrying, and the resources got later
+// are notified as changes.
+//
+// Only Nodes and Progress of opts are used. OpenShift resources and CSI
+// volume snapshots aren't watched, and ingresses are watched only in
+// networking.k8s.io/v1, which needs Kubernetes 1.19 or later.
+func WatchWithOptions(ctx context.Context, clientset kubernetes.Interface, namespace string, opts FetchOptions, onUpdate func(*Resources)) error {
+	// Cluster-scoped resources are watched in all namespaces regardless of WithNamespace
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	informerList := []cache.SharedIndexInformer{
+		factory.Core().V1().Services().Informer(),
+		factory.Core().V1().PersistentVolumeClaims().Informer(),
+		factory.Core().V1().ConfigMaps().Informer(),
+		factory.Core().V1().Secrets().Informer(),
+		factory.Core().V1().ServiceAccounts().Informer(),
+		factory.Core().V1().Pods().Informer(),
+		factory.Apps().V1().StatefulSets().Informer(),
+		factory.Apps().V1().DaemonSets().Informer(),
+		factory.Apps().V1().ReplicaSets().Informer(),
+		factory.Core().V1().ReplicationControllers().Informer(),
+		factory.Apps().V1().Deployments().Informer(),
+		factory.Batch().V1().Jobs().Informer(),
+		factory.Batch().V1beta1().CronJobs().Informer(),
+		factory.Networking().V1().Ingresses().Informer(),
+		factory.Autoscaling().V1().HorizontalPodAutoscalers().Informer(),
+		factory.Networking().V1().NetworkPolicies().Informer(),
+		factory.Policy().V1beta1().PodDisruptionBudgets().Informer(),
+		factory.Rbac().V1().Roles().Informer(),
+		factory.Rbac().V1().RoleBindings().Informer(),
+		factory.Core().V1().Endpoints().Informer(),
+		factory.Discovery().V1beta1().EndpointSlices().Informer(),
+		factory.Networking().V1().IngressClasses().Informer(),
+		factory.Core().V1().PersistentVolumes().Informer(),
+		factory.Storage().V1().StorageClasses().Informer(),
+		factory.Rbac().V1().ClusterRoles().Informer(),
+	}
+	if opts.Nodes {
+		informerList = append(informerList, factory.Core().V1().Nodes().Informer())
+	}
+
+	// changed is notified on changes, which is buffered not to block the informers
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	}
+	// failed has the indexes of the informers that failed to get the resources
+	// first, which are treated as synced
+	var failedMu sync.Mutex
+	failed := map[int]bool{}
+	synced := []cache.InformerSynced{}
+	for i, informer := range informerList {
+		i, informer := i, informer
+		informer.AddEventHandler(handler)
+		err := informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+			if informer.HasSynced() {
+				cache.DefaultWatchErrorHandler(r, err)
+				return
+			}
+			failedMu.Lock()
+			defer failedMu.Unlock()
+			// Reported only once, as the informer keeps retrying
+			if !failed[i] {
+				failed[i] = true
+				fmt.Fprintf(os.Stderr, "Failed to watch resources in namespace %q, which are left empty: %v\n", namespace, err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch resources in namespace %q: %v", namespace, err)
+		}
+		synced = append(synced, func() bool {
+			failedMu.Lock()
+			defer failedMu.Unlock()
+			return failed[i] || informer.HasSynced()
+		})
+	}
+
+	if opts.Progress != nil {
+		fmt.Fprintf(opts.Progress, "watching resources in namespace %q...\n", namespace)
+	}
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("failed to get resources in namespace %q to watch: %v", namespace, ctx.Err())
+	}
+
+	// snapshot returns the resources in the caches of the informers
+	snapshot := func() *Resources {
+		res := newEmptyResources(namespace)
+		res.clientset = clientset
+		for _, informer := range informerList {
+			for _, obj := range informer.GetStore().List() {
+				o, ok := obj.(runtime.Object)
+				if !ok {
+					continue
+				}
+				// Copied not to modify the caches, as the namespace is set for cluster-scoped ones
+				if err := res.addObject(o.DeepCopyObject()); err != nil {
+					// Skip object without metadata, which is never in the caches
+					continue
+				}
+			}
+		}
+		res.dropUnclaimedPersistentVolumes()
+		res.dropUnreferencedClusterRoles()
+		res.dropUnusedNodes()
+		return res
+	}
+
+	// Changes on getting the resources first are included in the first call
+	select {
+	case <-changed:
+	default:
+	}
+	onUpdate(snapshot())
+
+	// debounce and deadline are the timers to call onUpdate, which are nil if no change is waiting
+	// debounce is reset on each change, but deadline isn't.
+	var debounce, deadline <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+			debounce = time.After(watchDebounce)
+			if deadline == nil {
+				deadline = time.After(watchMaxWait)
+			}
+			continue
+		case <-debounce:
+		case <-deadline:
+		}
+		debounce, deadline = nil, nil
+		onUpdate(snapshot())
+	}
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package resources
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchWithOptionsForbidden(t *testing.T) {
+	clientset := newFakeClientset()
+	forbidList(clientset, "persistentvolumes")
+	forbidList(clientset, "secrets")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var res *Resources
+	var err error
+	stderr := captureStderr(t, func() {
+		err = WatchWithOptions(ctx, clientset, "default", FetchOptions{}, func(r *Resources) {
+			res = r
+			cancel()
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil {
+		t.Fatal("onUpdate isn't called")
+	}
+
+	if got := res.GetResourceNames("deploy"); len(got) != 1 || got[0] != "web" {
+		t.Errorf("deployments = %v, want [web]", got)
+	}
+	for _, resource := range []string{"persistentvolumes", "secrets"} {
+		if !strings.Contains(stderr, resource) {
+			t.Errorf("stderr = %q, want warning on %s", stderr, resource)
+		}
+	}
+}
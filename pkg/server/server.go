@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package server provides the HTTP handler to render the graph of k8s resources
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mkimuram/k8sviz/pkg/graph"
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// RenderPath is the path of the endpoint to render the graph
+	RenderPath = "/render"
+
+	defaultFormat = "svg"
+)
+
+var (
+	// contentTypes is the map of formats that can be rendered to their content types
+	contentTypes = map[string]string{
+		"dot":  "text/vnd.graphviz; charset=utf-8",
+		"svg":  "image/svg+xml",
+		"svgz": "image/svg+xml",
+		"png":  "image/png",
+		"jpg":  "image/jpeg",
+		"gif":  "image/gif",
+		"pdf":  "application/pdf",
+	}
+)
+
+// Handler renders the graph of k8s resources in the namespaces specified by requests
+type Handler struct {
+	clientset kubernetes.Interface
+	fetchOpts resources.FetchOptions
+	filter    func(*resources.Resources) error
+	dir       string
+	opts      graph.Options
+}
+
+// NewHandler returns a Handler that gets k8s resources with clientset and fetchOpts
+// filter is called with the resources in each namespace to filter them
+// before generating the graph, like by labels, which can be nil not to
+// filter. dir and opts are used to generate the graph in the same way as
+// graph.NewGraphForNamespaces.
+func NewHandler(clientset kubernetes.Interface, fetchOpts resources.FetchOptions, filter func(*resources.Resources) error, dir string, opts graph.Options) *Handler {
+	return &Handler{clientset: clientset, fetchOpts: fetchOpts, filter: filter, dir: dir, opts: opts}
+}
+
+// ServeHTTP renders the graph for GET /render?namespace=X&format=svg
+// namespace can be comma separated for multiple namespaces, and format is
+// one of dot, svg, svgz, png, jpg, gif and pdf, which is svg if omitted.
+// svgz is responded as svg with gzip content encoding.
+// The request context is used to get resources and to plot the graph, so
+// they are canceled when the client disconnects.
+// It responds 400 for bad requests and 500 with the error message on failures.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != RenderPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = defaultFormat
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("invalid format %q, must be one of dot, svg, svgz, png, jpg, gif or pdf", format), http.StatusBadRequest)
+		return
+	}
+	namespaces := []string{}
+	for _, ns := range strings.Split(query.Get("namespace"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	if len(namespaces) == 0 {
+		http.Error(w, "no namespace is specified", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	resList := []*resources.Resources{}
+	for _, ns := range namespaces {
+		res, err := resources.FetchResourcesWithOptions(ctx, h.clientset, ns, h.fetchOpts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if h.filter != nil {
+			if err := h.filter(res); err != nil {
+				http.Error(w, fmt.Sprintf("failed to filter resources in namespace %q: %v", ns, err), http.StatusInternalServerError)
+				return
+			}
+		}
+		resList = append(resList, res)
+	}
+
+	g, err := graph.NewGraphForNamespaces(resList, h.dir, h.opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The output is streamed to the client, so the error can be responded
+	// only if nothing is written yet
+	sw := &streamWriter{w: w, contentType: contentType, gzipped: format == "svgz"}
+	if format == "dot" {
+		err = g.WriteDotContext(ctx, sw)
+	} else {
+		err = g.PlotDotContext(ctx, sw, format)
+	}
+	if err != nil {
+		if !sw.written {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Failed to render graph for namespaces %v: %v\n", namespaces, err)
+	}
+}
+
+// streamWriter writes the header of the response with the content type at the first write
+type streamWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	// gzipped sets the content encoding to gzip, for the output already compressed
+	gzipped bool
+	written bool
+}
+
+// Write writes p to the response
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if !s.written {
+		s.w.Header().Set("Content-Type", s.contentType)
+		if s.gzipped {
+			s.w.Header().Set("Content-Encoding", "gzip")
+		}
+		s.w.WriteHeader(http.StatusOK)
+		s.written = true
+	}
+	return s.w.Write(p)
+}
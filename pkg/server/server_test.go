@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2021 k8sviz authors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mkimuram/k8sviz/pkg/graph"
+	"github.com/mkimuram/k8sviz/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// iconsDir is the directory that has icons directory in the repository
+const iconsDir = "../.."
+
+// newTestHandler returns the Handler for the fake clientset with deployments web and db in namespace "default"
+// Listing persistentvolumes is forbidden, like for the service account bound to a role only in the namespace.
+func newTestHandler(filter func(*resources.Resources) error) *Handler {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"}},
+	)
+	clientset.PrependReactor("list", "persistentvolumes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "persistentvolumes"}, "", nil)
+	})
+	return NewHandler(clientset, resources.FetchOptions{}, filter, iconsDir, graph.Options{})
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		wantCode        int
+		wantContentType string
+		wantBody        []string
+	}{
+		{name: "unknown path", method: http.MethodGet, target: "/unknown?namespace=default", wantCode: http.StatusNotFound},
+		{name: "post", method: http.MethodPost, target: RenderPath + "?namespace=default", wantCode: http.StatusMethodNotAllowed, wantBody: []string{"method POST not allowed"}},
+		{name: "bad format", method: http.MethodGet, target: RenderPath + "?namespace=default&format=bmp", wantCode: http.StatusBadRequest, wantBody: []string{`invalid format "bmp"`}},
+		{name: "missing namespace", method: http.MethodGet, target: RenderPath + "?format=dot", wantCode: http.StatusBadRequest, wantBody: []string{"no namespace is specified"}},
+		{
+			name:            "dot",
+			method:          http.MethodGet,
+			target:          RenderPath + "?namespace=default&format=dot",
+			wantCode:        http.StatusOK,
+			wantContentType: contentTypes["dot"],
+			wantBody:        []string{"digraph", "deploy_default__web", "deploy_default__db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestHandler(nil).ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); tt.wantContentType != "" && got != tt.wantContentType {
+				t.Errorf("content type = %q, want %q", got, tt.wantContentType)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body = %q, want %q in it", w.Body.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestServeHTTPWithFilter(t *testing.T) {
+	namespaces := []string{}
+	handler := newTestHandler(func(res *resources.Resources) error {
+		namespaces = append(namespaces, res.Namespace)
+		return res.FilterByName("^web$")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, RenderPath+"?namespace=default&format=dot", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if len(namespaces) != 1 || namespaces[0] != "default" {
+		t.Errorf("filtered namespaces = %v, want [default]", namespaces)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "deploy_default__web") || strings.Contains(body, "deploy_default__db") {
+		t.Errorf("body = %q, want only deploy web", body)
+	}
+}